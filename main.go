@@ -5,8 +5,8 @@ import (
 	"context"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
+	"time"
 
 	"github.com/schollz/progressbar/v3"
 	"github.com/projectdiscovery/gologger"
@@ -23,6 +23,13 @@ func init() {
 	config.UserAgents()
 }
 
+const (
+	// exitInterrupted is the exit status when the run is interrupted (128 + SIGINT)
+	exitInterrupted = 130
+	// stateInterval is how often the state is saved with -save-state
+	stateInterval = 10 * time.Second
+)
+
 // The main func
 func main() {
 	// Check if no arguments are provided (excluding the program name)
@@ -39,69 +46,133 @@ func main() {
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
 
-	// Start a goroutine To listen for signals and cancel the context on signal
+	// Start a goroutine To listen for signals and cancel the context on signal,
+	// a second signal exits right away without waiting for the in-flight requests
 	go func() {
 		select {
 		case sig := <-signalCh:
 			fmt.Println("\r\033[K")
-			fmt.Printf("[WARN] Caught keyboard: %v (Ctrl-C)\n", sig)
+			fmt.Printf("[WARN] Caught keyboard: %v (Ctrl-C), finishing in-flight requests, Ctrl-C again To force exit\n", sig)
 			fmt.Println("\r\033[K")
 			cancel()
 		case <-ctx.Done():
 			// Context canceled, no need To handle signals
+			return
 		}
+		<-signalCh
+		os.Exit(exitInterrupted)
 	}()
 
+	// Resume a scan, the configuration is read from the state file
+	var state opt.State
+	if config.Cfg.Resume != "" {
+		state = opt.LoadState(config.Cfg.Resume)
+	}
+
 	// Validate and process configurations
 	opt.ValidateConfig()
+	opt.StateConfig = config.Cfg
 
 	if !config.Cfg.FollowRedirect {
 		config.HttpClient.CheckRedirect = nil
 	}
+	// The client is built before the flags are parsed, apply the timeout now
+	config.HttpClient.Timeout = time.Duration(config.Cfg.To) * time.Second
+	opt.SetProxy(config.HttpClient, opt.Proxies)
+	opt.SetHostConcurrency(config.HttpClient, config.Cfg.HostConcurrency)
+	opt.SetIdleConns(config.HttpClient, config.Cfg.Concurrency)
+	if config.Cfg.RequestFile != "" {
+		opt.SetRawTransport(config.HttpClient)
+	}
+	if opt.ReplayProxy != nil {
+		opt.ReplayClient = opt.NewReplayClient(config.HttpClient, opt.ReplayProxy)
+		opt.StartReplay(cmd.Limiter)
+	}
 
 	// Read wordlist and URLs
-	words, urls := opt.ReadInputFiles(config.Cfg)
+	wordlists, urls := opt.ReadInputFiles(config.Cfg)
 
-	if config.Cfg.OutputFile != "" {
-		file, err := os.Create(config.Cfg.OutputFile)
-		if err != nil {
-			gologger.Fatal().Msgf("Error creating success file: %v", err)
-		}
-		defer file.Close()
-		config.Cfg.SuccessFile = file
+	// Read the raw request file, it sets the method, headers, body and targets
+	if config.Cfg.RequestFile != "" {
+		urls = opt.ReadRequestFile(&config.Cfg, urls)
 	}
 
-	// Use a WaitGroup To wait for all goroutines To finish
-	var wg sync.WaitGroup
+	// Limit the requests per second, from the calibration requests on
+	cmd.Limiter.SetRate(config.Cfg.Rate)
+
+	// Learn the baselines of the targets, before the banner reports them
+	if config.Cfg.AutoCalibration {
+		opt.Matchers.Calibration = cmd.Calibrate(ctx, wordlists, urls)
+	}
 
-	// Define a progress bar pointer
+	// Create the success file, written in the -of format
+	opt.OpenOutputFile()
+
+	// Write the findings of the resumed scan back To the output file
+	if config.Cfg.Resume != "" {
+		opt.RestoreFindings(state)
+	}
+
+	// Define a progress bar pointer, a resumed scan only counts the remaining requests
 	var bar *progressbar.ProgressBar
-	if config.Cfg.WebCache && config.Cfg.WordlistFile == "" {
-		bar = opt.Progbar(len(urls))
+	if config.Cfg.WebCache && len(wordlists) == 0 {
+		bar = opt.Progbar(len(urls) - int(state.Completed))
 	} else {
-		bar = opt.Progbar(len(words) * len(urls))
+		bar = opt.Progbar(opt.CountCombinations(wordlists, config.Cfg.Mode)*len(urls) - int(state.Completed))
 	}
 
-	// Create a semaphore To limit concurrency
-	semaphore := make(chan struct{}, config.Cfg.Concurrency)
-
-	// Start the requests
-	cmd.StartRequests(ctx, &wg, semaphore, bar, words, urls)
+	// Save the state periodically, so a killed scan can be resumed
+	tracker := cmd.NewTracker(len(urls), state.Progress)
+	done := make(chan struct{})
+	if config.Cfg.SaveState != "" {
+		go func() {
+			ticker := time.NewTicker(stateInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := opt.SaveState(config.Cfg.SaveState, tracker.Progress()); err != nil {
+						gologger.Error().Msgf("Error saving state file %s: %v", config.Cfg.SaveState, err)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
 
-	// Start a goroutine To close the results channel once all requests are done
-	go func() {
-		wg.Wait()
-		close(semaphore)
-	}()
+	// Change the rate while running from the lines typed on stdin, only with -rate,
+	// so a scan run in the background never reads the terminal
+	if config.Cfg.Rate > 0 {
+		go cmd.ReadRate(ctx, os.Stdin, bar)
+	}
 
-	// Optionally wait for a user interrupt To exit gracefully
-	select {
-	case <-ctx.Done():
-		// Context canceled, exit gracefully
+	// Start the requests, at most -c at a time, it returns once they are all done,
+	// or once the in-flight requests are drained after an interrupt
+	start := time.Now()
+	cmd.StartRequests(ctx, bar, tracker, wordlists, urls)
+	close(done)
+	interrupted := ctx.Err() != nil
+
+	if interrupted {
+		// Leave the progress bar where it stopped
+		fmt.Fprintln(os.Stderr)
+		opt.PrintSaveState(tracker.Progress())
+	} else {
 		bar.Finish()
-		return
-	default:
+		// The scan is complete, there is nothing left To resume
+		if config.Cfg.SaveState != "" || config.Cfg.Resume != "" {
+			os.Remove(opt.StatePath())
+		}
+		opt.RemoveFindings()
 	}
 
-	bar.Finish()
+	// Send the queued replays, then flush and close the output files before exiting
+	opt.StopReplay()
+	opt.CloseFiles()
+	opt.PrintSummary(time.Since(start), interrupted)
+
+	if interrupted {
+		os.Exit(exitInterrupted)
+	}
 }
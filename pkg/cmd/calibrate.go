@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"golang.org/x/exp/rand"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/matcher"
+	"github.com/SpeedyQweku/qfuzz/pkg/opt"
+)
+
+// calibrationChars are the characters of the random calibration strings
+const calibrationChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// CalibrationStrings returns the strings sent To calibrate a keyword position, random strings
+// of different lengths, so the counts echoing the input are left out, then the -acc strings
+func CalibrationStrings() []string {
+	strs := []string{
+		randomString(8),
+		randomString(16),
+		randomString(24),
+		".ht" + randomString(12),
+		"admin" + randomString(12) + "/",
+	}
+	return append(strs, config.Cfg.CustomCalibration...)
+}
+
+// randomString returns a random string of n calibration characters
+func randomString(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = calibrationChars[rand.Intn(len(calibrationChars))]
+	}
+	return string(b)
+}
+
+// Calibrate sends calibration requests for every target URL and keyword position, and learns
+// the baselines of the responses. The other keywords keep the first word of their wordlist.
+func Calibrate(ctx context.Context, wordlists []config.Wordlist, urls []string) *matcher.Calibration {
+	calibration := matcher.NewCalibration()
+	if len(wordlists) == 0 {
+		return calibration
+	}
+	defaults, err := opt.FirstWords(wordlists)
+	if err != nil {
+		gologger.Error().Msgf("Error reading wordlist: %v", err)
+		return calibration
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, config.Cfg.Concurrency)
+	for _, url := range urls {
+		for _, wordlist := range wordlists {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				wg.Wait()
+				return calibration
+			}
+			wg.Add(1)
+			go func(url, keyword string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				var responses []*matcher.Response
+				for _, str := range CalibrationStrings() {
+					inputs := make(map[string]string, len(defaults))
+					for k, word := range defaults {
+						inputs[k] = word
+					}
+					inputs[keyword] = str
+					if Limiter.Wait(ctx) != nil {
+						return
+					}
+					if response := SendRequest(ctx, Job{Seq: -1, URL: url, Inputs: inputs}); response != nil {
+						responses = append(responses, response)
+					}
+				}
+				if len(responses) == 0 {
+					return
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				calibration.Learn(url, responses)
+			}(url, wordlist.Keyword)
+		}
+	}
+	wg.Wait()
+	return calibration
+}
@@ -2,21 +2,23 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	neturl "net/url"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/projectdiscovery/gologger"
-	"github.com/schollz/progressbar/v3"
 
 	"github.com/SpeedyQweku/qfuzz/pkg/common"
 	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/matcher"
 	"github.com/SpeedyQweku/qfuzz/pkg/opt"
+	"github.com/SpeedyQweku/qfuzz/pkg/payload"
+	"github.com/SpeedyQweku/qfuzz/pkg/rawhttp"
 )
 
 // Declare and initialize a sync.Pool for http.Request objects and http.Response objects.
@@ -39,70 +41,70 @@ var (
 )
 
 // Making http request func
-func MakeRequest(url, word string, wg *sync.WaitGroup, semaphore chan struct{}, ctx context.Context, cfg config.Config, bar *progressbar.ProgressBar) {
-	defer func() {
-		<-semaphore // release semaphore
-		wg.Done()
-		bar.Add(1)
-	}()
-
-	var result config.Result
-	fullURL := opt.ProcessUrls(url, word, cfg)
-	cfg.PostData = strings.Replace(cfg.PostData, "FUZZ", word, 1)
-
-	headers := make([]string, len(cfg.Headers))
-	copy(headers, cfg.Headers)
-	if opt.CheckFUZZheader(cfg.Headers) {
-		for i, item := range headers {
-			headers[i] = strings.Replace(item, "FUZZ", word, 1)
+func MakeRequest(ctx context.Context, job Job) {
+	response := SendRequest(ctx, job)
+	if response == nil {
+		return
+	}
+
+	if config.Cfg.WebCache {
+		for key, val := range response.Header {
+			if opt.DetectWebCache(key, val, response.Result.URL, &config.Mu) {
+				break
+			}
 		}
 	}
 
+	// Process the result
+	opt.ProcessResult(response)
+}
+
+// SendRequest makes the request of the job, and returns its response with the result built from it.
+// It returns nil if the request could not be made.
+func SendRequest(ctx context.Context, job Job) *matcher.Response {
+	cfg := &config.Cfg
+	inputs := job.Inputs
+
+	fullURL := opt.ProcessUrls(job.URL, inputs, cfg)
+	postData := payload.Replace(cfg.PostData, inputs)
+	headers := payload.ReplaceAll(cfg.Headers, inputs)
+
 	// Reuse http.Request and http.Response using sync.Pool
 	request := AcquireRequest()
 	defer ReleaseRequest(request)
-	request.URL, _ = neturl.Parse(fullURL)
+	var err error
+	request.URL, err = neturl.Parse(fullURL)
+	if err != nil {
+		common.DebugModeEr(cfg.Debug, fullURL, err)
+		return nil
+	}
 
-	// Set the HTTP method
-	if cfg.HttpMethod != "" {
-		request.Method = strings.ToUpper(cfg.HttpMethod)
+	// Set the HTTP method, a fuzzed method is sent as is
+	if payload.Contains(cfg.HttpMethod, inputs) {
+		request.Method = payload.Replace(cfg.HttpMethod, inputs)
+	} else if cfg.HttpMethod != "" {
+		request.Method = strings.ToUpper(payload.Replace(cfg.HttpMethod, inputs))
 	} else {
 		request.Method = "GET"
 	}
 
-	// Set User-Agent in the request header
-	if request.Header == nil {
-		request.Header = make(http.Header)
-	}
-	if len(headers) != 0 {
-		for _, pair := range headers {
-			parts := strings.SplitN(pair, ":", 2)
-			if len(parts) == 2 {
-				key := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
-				request.Header.Set(key, value)
-			}
-		}
-
-		// Check if "User-Agent" header is present, and if not, add it
-		if cfg.RandomUserAgent {
-			if _, exists := request.Header["User-Agent"]; !exists {
-				request.Header.Set("User-Agent", GetRandomUserAgent())
-			}
-		}
-	} else if len(headers) == 0 && cfg.RandomUserAgent {
-		request.Header.Set("User-Agent", GetRandomUserAgent())
+	// Set the headers and User-Agent in the request header
+	SetHeaders(request, headers, cfg)
+	// A raw request is sent with its headers in the order of the file
+	if cfg.RequestFile != "" {
+		ctx = rawhttp.WithOrder(ctx, HeaderNames(headers))
 	}
 
 	// If PostData is provided, include it in the request body
-	if cfg.PostData != "" {
-		request.Body = ioutil.NopCloser(strings.NewReader(cfg.PostData))
+	if postData != "" {
+		request.ContentLength = int64(len(postData))
+		request.Body = ioutil.NopCloser(strings.NewReader(postData))
 		request.GetBody = func() (io.ReadCloser, error) {
-			return ioutil.NopCloser(strings.NewReader(cfg.PostData)), nil
+			return ioutil.NopCloser(strings.NewReader(postData)), nil
 		}
 
-		// Check if "Content-Type" header is present, and if not, add it
-		if _, exists := request.Header["Content-Type"]; !exists {
+		// Check if "Content-Type" header is present, and if not, add it, a raw request is sent as captured
+		if !HasHeader(request.Header, "Content-Type") && cfg.RequestFile == "" {
 			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		}
 	}
@@ -113,82 +115,33 @@ func MakeRequest(url, word string, wg *sync.WaitGroup, semaphore chan struct{},
 	response := AcquireResponse()
 	defer ReleaseResponse(response)
 
-	// Optionally wait for a user interrupt To exit gracefully
-	select {
-	case <-ctx.Done():
-		// Context canceled, exit gracefully
-		// bar.Finish()
-		os.Exit(0)
-		return
-	default:
-	}
-
-	// Start timer
-	reqstart := time.Now()
-	// Make the HTTP request
-	resp, err := config.HttpClient.Do(request.WithContext(ctx))
-	// Calculate elapsed time
-	reqelapsed := time.Since(reqstart).Round(time.Millisecond)
-
+	// Make the HTTP request, retried on the -retry-codes, the last response is the one matched
+	resp, bodyBuffer, reqelapsed, err := doRequest(ctx, request, fullURL)
 	if err != nil {
 		common.DebugModeEr(cfg.Debug, fullURL, err)
-		return
+		return nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusTooManyRequests {
-		RateLimit(fullURL, resp.StatusCode, request)
-	}
-
-	// var bodyBuffer bytes.Buffer
-	// _, err = io.Copy(&bodyBuffer, resp.Body)
-	// if err != nil {
-	// 	debugModeEr(cfg.Debug, fullURL, err)
-	// 	// return nil, err
-	// }
-	bodyBuffer, err := opt.ReadResponseBody(resp, fullURL)
-	if err != nil {
-		common.DebugModeEr(cfg.Debug, fullURL, err)
-	}
-
-	if len(cfg.MatchStrings) != 0 || len(cfg.FilterStrings) != 0 {
-		result.Match = opt.DetectBodyMatch(fullURL, []byte(bodyBuffer))
-	}
-
-	result.StatusCode = resp.StatusCode
-	result.Status = resp.Status
-	result.URL = fullURL
-	result.Ttaken = reqelapsed
-
-	if resp.ContentLength != -1 {
-		result.ContentSize = resp.ContentLength
-	} else {
-		bodyBytes := bodyBuffer
-		result.ContentSize = int64(len(bodyBytes))
-	}
+	result := NewResult(job, request, resp, bodyBuffer, fullURL, reqelapsed)
+	return &matcher.Response{Result: &result, Target: job.URL, Body: bodyBuffer, Header: resp.Header, Request: replayRequest(ctx, request)}
+}
 
-	if cfg.WebCache {
-		for key, val := range resp.Header {
-			if opt.DetectWebCache(key, val, fullURL, &config.Mu) {
-				break
-			}
-		}
+// replayRequest returns the request To send again through -replay-proxy, nil without it.
+// It is a shallow copy, so it outlives the pooled request, only a matched one is cloned.
+func replayRequest(ctx context.Context, request *http.Request) *http.Request {
+	if config.Cfg.ReplayProxy == "" {
+		return nil
 	}
-
-	// Process the result
-	opt.ProcessResult(&result, cfg)
+	// The context keeps the header order of a raw request
+	return request.WithContext(context.WithoutCancel(ctx))
 }
 
 // http request just for web cache
-func WebCacheRequest(url string, wg *sync.WaitGroup, semaphore chan struct{}, ctx context.Context, cfg config.Config, bar *progressbar.ProgressBar) {
-	defer func() {
-		<-semaphore // release semaphore
-		wg.Done()
-		bar.Add(1)
-	}()
+func WebCacheRequest(ctx context.Context, job Job) {
+	cfg := &config.Cfg
+	url := job.URL
 
 	var fullURL string
-	var result config.Result
 
 	urls, err := neturl.Parse(url)
 	if err != nil {
@@ -213,40 +166,22 @@ func WebCacheRequest(url string, wg *sync.WaitGroup, semaphore chan struct{}, ct
 		request.Method = "GET"
 	}
 
-	// Set User-Agent in the request header
-	if request.Header == nil {
-		request.Header = make(http.Header)
-	}
-
-	if len(cfg.Headers) != 0 {
-		for _, pair := range cfg.Headers {
-			parts := strings.SplitN(pair, ":", 2)
-			if len(parts) == 2 {
-				key := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
-				request.Header.Set(key, value)
-			}
-		}
-
-		// Check if "User-Agent" header is present, and if not, add it
-		if cfg.RandomUserAgent {
-			if _, exists := request.Header["User-Agent"]; !exists {
-				request.Header.Set("User-Agent", GetRandomUserAgent())
-			}
-		}
-	} else if len(cfg.Headers) == 0 && cfg.RandomUserAgent {
-		request.Header.Set("User-Agent", GetRandomUserAgent())
+	// Set the headers and User-Agent in the request header
+	SetHeaders(request, cfg.Headers, cfg)
+	if cfg.RequestFile != "" {
+		ctx = rawhttp.WithOrder(ctx, HeaderNames(cfg.Headers))
 	}
 
 	// If PostData is provided, include it in the request body
 	if cfg.PostData != "" {
+		request.ContentLength = int64(len(cfg.PostData))
 		request.Body = ioutil.NopCloser(strings.NewReader(cfg.PostData))
 		request.GetBody = func() (io.ReadCloser, error) {
 			return ioutil.NopCloser(strings.NewReader(cfg.PostData)), nil
 		}
 
-		// Check if "Content-Type" header is present, and if not, add it
-		if _, exists := request.Header["Content-Type"]; !exists {
+		// Check if "Content-Type" header is present, and if not, add it, a raw request is sent as captured
+		if !HasHeader(request.Header, "Content-Type") && cfg.RequestFile == "" {
 			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		}
 	}
@@ -254,64 +189,69 @@ func WebCacheRequest(url string, wg *sync.WaitGroup, semaphore chan struct{}, ct
 	response := AcquireResponse()
 	defer ReleaseResponse(response)
 
-	// Optionally wait for a user interrupt To exit gracefully
-	select {
-	case <-ctx.Done():
-		// Context canceled, exit gracefully
-		// bar.Finish()
-		os.Exit(0)
-		return
-	default:
-	}
-
-	// Start timer
-	reqstart := time.Now()
-	// Make the HTTP request
-	resp, err := config.HttpClient.Do(request.WithContext(ctx))
-	// Calculate elapsed time
-	reqelapsed := time.Since(reqstart).Round(time.Millisecond)
+	// Make the HTTP request, retried on the -retry-codes, the last response is the one checked
+	resp, bodyBuffer, reqelapsed, err := doRequest(ctx, request, fullURL)
 	if err != nil {
 		common.DebugModeEr(cfg.Debug, fullURL, err)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusTooManyRequests {
-		RateLimit(fullURL, resp.StatusCode, request)
-	}
-
-	bodyBuffer, err := opt.ReadResponseBody(resp, fullURL)
-	if err != nil {
-		common.DebugModeEr(cfg.Debug, fullURL, err)
-	}
-
-	if len(cfg.MatchStrings) != 0 || len(cfg.FilterStrings) != 0 {
-		result.Match = opt.DetectBodyMatch(fullURL, []byte(bodyBuffer))
-	}
+	result := NewResult(job, request, resp, bodyBuffer, fullURL, reqelapsed)
 
 	if cfg.WebCache {
-		if resp.StatusCode == http.StatusTooManyRequests {
-			RateLimit(fullURL, resp.StatusCode, request)
-		}
 		for key, val := range resp.Header {
 			if opt.DetectWebCache(key, val, fullURL, &config.Mu) {
-
-				result.StatusCode = resp.StatusCode
-				result.Status = resp.Status
-				result.URL = fullURL
-				result.Ttaken = reqelapsed
-
-				if resp.ContentLength != -1 {
-					result.ContentSize = resp.ContentLength
-				} else {
-					bodyBytes := bodyBuffer
-					result.ContentSize = int64(len(bodyBytes))
-				}
-
 				// Process the result
-				opt.ProcessResult(&result, cfg)
+				opt.ProcessResult(&matcher.Response{Result: &result, Target: job.URL, Body: bodyBuffer, Header: resp.Header, Request: replayRequest(ctx, request)})
 				break
 			}
 		}
 	}
 }
+
+// doRequest sends the request, and sends it again while its status code is one of -retry-codes, up To
+// -retries times. It returns the last response with its body read, and the time the last attempt took.
+// An in-flight request is let finish (bounded by -timeout) on interrupt, but no retry is started.
+func doRequest(ctx context.Context, request *http.Request, fullURL string) (*http.Response, []byte, time.Duration, error) {
+	for attempt := 0; ; attempt++ {
+		// The body of the previous attempt was read, send a new copy of it
+		if attempt > 0 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			request.Body = body
+		}
+
+		// Start timer
+		reqstart := time.Now()
+		resp, err := config.HttpClient.Do(request.WithContext(context.WithoutCancel(ctx)))
+		// Calculate elapsed time
+		reqelapsed := time.Since(reqstart).Round(time.Millisecond)
+		config.Stat.Requests.Add(1)
+		if err != nil {
+			config.Stat.Errors.Add(1)
+			return nil, nil, reqelapsed, err
+		}
+		bodyBuffer, _ := opt.ReadResponseBody(resp, fullURL)
+		resp.Body.Close()
+
+		if attempt >= config.Cfg.Retries || !opt.RetryCodes.Contains(int64(resp.StatusCode)) {
+			return resp, bodyBuffer, reqelapsed, nil
+		}
+		wait := RetryDelay(resp.Header.Get("Retry-After"), attempt)
+		common.DebugModeEr(config.Cfg.Debug, fullURL, fmt.Errorf("%s, retry %d/%d in %v", resp.Status, attempt+1, config.Cfg.Retries, wait))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, bodyBuffer, reqelapsed, nil
+		}
+		// The retries take a token of the -rate bucket like any request
+		if Limiter.Wait(ctx) != nil {
+			return resp, bodyBuffer, reqelapsed, nil
+		}
+	}
+}
@@ -1,14 +1,15 @@
 package cmd
 
 import (
-	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/exp/rand"
 
-	"github.com/SpeedyQweku/qfuzz/pkg/common"
 	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/opt"
 )
 
 
@@ -21,7 +22,10 @@ func AcquireRequest() *http.Request {
 func ReleaseRequest(req *http.Request) {
 	req.URL = nil
 	req.Body = nil
+	req.GetBody = nil
+	req.ContentLength = 0
 	req.Header = nil
+	req.Host = ""
 	RequestPool.Put(req)
 }
 
@@ -35,35 +39,105 @@ func ReleaseResponse(resp *http.Response) {
 	ResponsePool.Put(resp)
 }
 
+// HeaderNames returns the names of the "Name: value" headers, in their order
+func HeaderNames(headers []string) []string {
+	names := make([]string, 0, len(headers))
+	for _, pair := range headers {
+		if name, _, found := strings.Cut(pair, ":"); found {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	return names
+}
+
+// Set the headers on the request, keeping the header names as given
+func SetHeaders(request *http.Request, headers []string, cfg *config.Config) {
+	if request.Header == nil {
+		request.Header = make(http.Header)
+	}
+	for _, pair := range headers {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) == 2 {
+			key := strings.TrimSpace(parts[0])
+			value := strings.TrimSpace(parts[1])
+			switch {
+			case strings.EqualFold(key, "Host"):
+				// Go sends the Host header from request.Host only
+				request.Host = value
+			case strings.EqualFold(key, "User-Agent"):
+				// Go adds its own User-Agent unless the canonical one is set
+				request.Header.Set(key, value)
+			default:
+				request.Header[key] = append(request.Header[key], value)
+			}
+		}
+	}
+
+	// Check if "User-Agent" header is present, and if not, add it
+	if cfg.RandomUserAgent && !HasHeader(request.Header, "User-Agent") {
+		request.Header.Set("User-Agent", GetRandomUserAgent())
+	}
+}
+
+// Check if the header is present, whatever the casing of its name
+func HasHeader(header http.Header, name string) bool {
+	for key := range header {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewResult builds the result of a request from its response
+func NewResult(job Job, request *http.Request, resp *http.Response, body []byte, fullURL string, elapsed time.Duration) config.Result {
+	result := config.Result{
+		StatusCode:       resp.StatusCode,
+		Status:           resp.Status,
+		URL:              fullURL,
+		Input:            job.Inputs,
+		Method:           request.Method,
+		Words:            opt.BodyWords(body),
+		Lines:            opt.BodyLines(body),
+		RedirectLocation: resp.Header.Get("Location"),
+		ContentType:      resp.Header.Get("Content-Type"),
+		Ttaken:           elapsed,
+		DurationMs:       elapsed.Milliseconds(),
+		Timestamp:        time.Now(),
+		Job:              job.Seq,
+	}
+
+	if resp.ContentLength != -1 {
+		result.ContentSize = resp.ContentLength
+	} else {
+		result.ContentSize = int64(len(body))
+	}
+	return result
+}
+
 // Select a random User-Agent from the list
 func GetRandomUserAgent() string {
 	// panic(len(config.Cfg.UserAgents))
 	return config.Cfg.UserAgents[rand.Intn(len(config.Cfg.UserAgents))]
 }
 
-// rate limit for the http request
-func RateLimit(fullURL string, sCode int, request *http.Request) {
-	retryAttempts := 0
-	maxRetries := config.Cfg.Retries
-	for retryAttempts < maxRetries {
-		retryAttempts++
-		// Exponential backoff: wait for 2^retryAttempts seconds before retrying
-		backoffDuration := time.Duration(2<<retryAttempts+1) * time.Second
-		// log.Printf(Yellow+"Received 429 Too Many Requests. Retrying in %v seconds..."+Reset, backoffDuration.Seconds())
-		time.Sleep(backoffDuration)
-		// config.Cfg.httpClient To httpClient
-		resp, err := config.HttpClient.Do(request)
-		if err != nil {
-			common.DebugModeEr(config.Cfg.Debug, fullURL, err)
-			return
+// RetryDelay returns the wait before a retry, the Retry-After header (seconds or a date) if there is one,
+// or else an exponential backoff from a second with jitter, both capped at -retry-max-wait
+func RetryDelay(retryAfter string, attempt int) time.Duration {
+	maxWait := time.Duration(config.Cfg.RetryMaxWait) * time.Second
+	if retryAfter = strings.TrimSpace(retryAfter); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, maxWait)
 		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusTooManyRequests {
-			break
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return min(max(time.Until(date), 0), maxWait)
 		}
 	}
-	if sCode == http.StatusTooManyRequests {
-		common.DebugModeEr(config.Cfg.Debug, fullURL, errors.New("429 TooManyRequests"))
-		return
+
+	backoff := maxWait
+	if attempt < 30 {
+		backoff = min(time.Second<<attempt, maxWait)
 	}
+	// Half of the backoff is random, so the throttled requests are not all retried at once
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
 }
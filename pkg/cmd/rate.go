@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/schollz/progressbar/v3"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/opt"
+	"github.com/SpeedyQweku/qfuzz/pkg/ratelimit"
+)
+
+// Limiter is the -rate token bucket shared by all the requests
+var Limiter = ratelimit.New(0)
+
+// ReadRate changes the rate from the lines of r while the scan runs, a line is
+// a number of requests per second, optionally after "rate" (e.g., rate 50), 0 removes the limit
+func ReadRate(ctx context.Context, r io.Reader, bar *progressbar.ProgressBar) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return
+		}
+		line := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "rate"))
+		if line == "" {
+			continue
+		}
+		rate, err := strconv.Atoi(line)
+		if err != nil || rate < 0 {
+			gologger.Error().Msgf("%sInvalid value: %s, For -rate (0 or more)%s", config.Red, line, config.Reset)
+			continue
+		}
+		Limiter.SetRate(rate)
+		bar.Describe(opt.BarDescription(rate))
+	}
+}
@@ -1,30 +1,531 @@
 package cmd
 
 import (
+	"container/heap"
 	"context"
+	neturl "net/url"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/schollz/progressbar/v3"
 
 	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/opt"
+	"github.com/SpeedyQweku/qfuzz/pkg/ratelimit"
 )
 
+// Job is a single request To make, it only holds what varies between requests
+type Job struct {
+	Seq    int64             // Seq is the position of the job in the run, combinations first then URLs.
+	URL    string            // URL is the target URL, before the keywords are replaced.
+	Inputs map[string]string // Inputs maps each keyword To its word, nil for web cache only requests.
+}
+
+// Tracker records the completed jobs, and how many combinations are all done for each target URL
+type Tracker struct {
+	mu   sync.Mutex
+	next []int64        // next is the number of combinations from the start all done, for each URL.
+	done map[int64]bool // done holds the completed jobs past the next combination of their URL.
+}
+
+// NewTracker returns a tracker for the URLs, progress is where a resumed scan stopped, nil for a new one
+func NewTracker(urls int, progress []int64) *Tracker {
+	next := make([]int64, urls)
+	copy(next, progress)
+	return &Tracker{next: next, done: make(map[int64]bool)}
+}
+
+// Done marks the job as completed
+func (t *Tracker) Done(seq int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	urls := int64(len(t.next))
+	url := seq % urls
+	t.done[seq] = true
+	for t.done[t.next[url]*urls+url] {
+		delete(t.done, t.next[url]*urls+url)
+		t.next[url]++
+	}
+}
 
-// startRequests starts the HTTP requests using goroutines
-func StartRequests(ctx context.Context, wg *sync.WaitGroup, semaphore chan struct{}, bar *progressbar.ProgressBar, words, urls []string) {
-	if config.Cfg.WebCache && config.Cfg.WordlistFile == "" {
-		for _, url := range urls {
-			wg.Add(1)               // Increment the wait group counter
-			semaphore <- struct{}{} // acquire semaphore
-			go WebCacheRequest(url, wg, semaphore, ctx, config.Cfg, bar)
+// Progress returns the number of combinations from the start all done, for each URL
+func (t *Tracker) Progress() []int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.next)
+}
+
+// queuePerHost is how many jobs a host may have queued. A host with a full queue while another host of
+// the same read of the wordlists runs low falls behind, and waits for a read of its own.
+const queuePerHost = 64
+
+// lowPerHost is the queue length under which a host needs more jobs
+const lowPerHost = queuePerHost / 4
+
+// maxFeeds is how many reads of the wordlists may be open at once
+const maxFeeds = 8
+
+// host is a hostname of the target URLs, with its queued jobs and its limits
+type host struct {
+	queue   []Job              // queue holds the jobs of the host not handed To a worker yet, in seq order.
+	running int                // running is the number of requests in flight To the host.
+	limiter *ratelimit.Limiter // limiter is the -host-rate bucket of the host.
+
+	index     int       // index is the position of the host in the ready or throttled heap, -1 in neither.
+	throttled bool      // throttled is set while the host waits for a -host-rate token.
+	until     time.Time // until is when a throttled host gets its token.
+
+	urls  []int64 // urls are the indexes of the target URLs of the host.
+	combo int64   // combo is the index of the next combination To queue on the host.
+	feed  *feed   // feed is the read of the wordlists queuing the jobs of the host, nil while it waits for one.
+	low   bool    // low is set while the queue of the host runs low.
+}
+
+// feed is a read of the wordlists, each combination it reads is queued on the hosts at its position
+type feed struct {
+	combinations <-chan map[string]string
+	cancel       context.CancelFunc
+	combo        int64   // combo is the index of the next combination read.
+	hosts        []*host // hosts are the hosts at combo, the combination read is queued on them.
+	low          int     // low is the number of hosts whose queue runs low.
+}
+
+// hostHeap is a min-heap of hosts, ordered by less
+type hostHeap struct {
+	hosts []*host
+	less  func(a, b *host) bool
+}
+
+func (h *hostHeap) Len() int           { return len(h.hosts) }
+func (h *hostHeap) Less(i, j int) bool { return h.less(h.hosts[i], h.hosts[j]) }
+func (h *hostHeap) Swap(i, j int) {
+	h.hosts[i], h.hosts[j] = h.hosts[j], h.hosts[i]
+	h.hosts[i].index = i
+	h.hosts[j].index = j
+}
+
+func (h *hostHeap) Push(x any) {
+	x.(*host).index = len(h.hosts)
+	h.hosts = append(h.hosts, x.(*host))
+}
+
+func (h *hostHeap) Pop() any {
+	last := h.hosts[len(h.hosts)-1]
+	h.hosts = h.hosts[:len(h.hosts)-1]
+	last.index = -1
+	return last
+}
+
+// hosts groups the target URLs by hostname, in the order they are given, and returns the host of each URL.
+// A keyword in the hostname is part of it, so the hosts it gives share the limits.
+func hosts(urls []string) (list []*host, hostOf []*host) {
+	byName := make(map[string]*host)
+	for i, url := range urls {
+		name := url
+		if !strings.Contains(name, "://") {
+			name = "https://" + name
+		}
+		if u, err := neturl.Parse(name); err == nil {
+			name = u.Hostname()
 		}
+
+		h, ok := byName[name]
+		if !ok {
+			h = &host{limiter: ratelimit.New(config.Cfg.HostRate), index: -1}
+			byName[name] = h
+			list = append(list, h)
+		}
+		h.urls = append(h.urls, int64(i))
+		hostOf = append(hostOf, h)
+	}
+	return list, hostOf
+}
+
+// scheduler holds the queued jobs of each host, and hands them out in seq order as the host limits allow.
+// The hosts with a queued job and a free slot are kept in the ready heap by the seq of their first job,
+// those waiting for a -host-rate token in the throttled heap by when they get it.
+//
+// The jobs are queued by reads of the wordlists, each feeding the hosts at its position as their queues
+// run low. A slow host falls behind the others once its queue is full, and is fed later by a read of
+// its own, so it never holds the other hosts back and the queues stay bounded.
+type scheduler struct {
+	mu        sync.Mutex
+	hosts     []*host
+	hostOf    []*host       // hostOf is the host of each target URL, the URL of a job is at Seq % len(hostOf).
+	queued    int           // queued is the number of jobs in the host queues.
+	ready     hostHeap      // ready are the hosts that may send their first job.
+	throttled hostHeap      // throttled are the hosts that may send their first job once they get a token.
+	wake      chan struct{} // wake is signaled once a request is done, so a waiting job may go.
+
+	urls     []string          // urls are the target URLs.
+	progress []int64           // progress is the number of combinations done for each URL before a resume.
+	total    int64             // total is the number of combinations.
+	feeds    []*feed           // feeds are the open reads of the wordlists.
+	waiting  map[int64][]*host // waiting are the hosts without a feed, by position.
+	behind   int               // behind is the number of waiting hosts whose queue runs low.
+}
+
+// newScheduler returns a scheduler for the target URLs and the number of combinations, progress is
+// where a resumed scan stopped for each URL
+func newScheduler(urls []string, progress []int64, total int64) *scheduler {
+	list, hostOf := hosts(urls)
+	s := &scheduler{
+		hosts:     list,
+		hostOf:    hostOf,
+		ready:     hostHeap{less: func(a, b *host) bool { return a.queue[0].Seq < b.queue[0].Seq }},
+		throttled: hostHeap{less: func(a, b *host) bool { return a.until.Before(b.until) }},
+		wake:      make(chan struct{}, 1),
+		urls:      urls,
+		progress:  make([]int64, len(urls)),
+		total:     total,
+		waiting:   make(map[int64][]*host),
+	}
+	copy(s.progress, progress)
+
+	// A host starts at the first combination one of its URLs has not done
+	for _, h := range list {
+		h.combo = total
+		for _, url := range h.urls {
+			h.combo = min(h.combo, s.progress[url])
+		}
+		if h.combo < total {
+			s.park(h)
+			s.recount(h)
+		}
+	}
+	return s
+}
+
+// update puts the host in the ready heap, moves it or takes it out, after its queue or slots changed.
+// A throttled host stays where it is until it gets its token.
+func (s *scheduler) update(h *host) {
+	if h.throttled {
+		return
+	}
+	ready := len(h.queue) > 0 && (config.Cfg.HostConcurrency <= 0 || h.running < config.Cfg.HostConcurrency)
+	switch {
+	case h.index >= 0 && ready:
+		heap.Fix(&s.ready, h.index)
+	case h.index >= 0:
+		heap.Remove(&s.ready, h.index)
+	case ready:
+		heap.Push(&s.ready, h)
+	}
+}
+
+// next pops the queued job with the lowest seq whose host has a free slot and a -host-rate token.
+// Without one, it returns how long until a host gets a token, 0 if only a done request can help.
+func (s *scheduler) next() (Job, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for s.throttled.Len() > 0 && !s.throttled.hosts[0].until.After(now) {
+		h := heap.Pop(&s.throttled).(*host)
+		h.throttled = false
+		s.update(h)
+	}
+
+	for s.ready.Len() > 0 {
+		h := s.ready.hosts[0]
+		if d := h.limiter.Take(); d > 0 {
+			heap.Pop(&s.ready)
+			h.throttled, h.until = true, now.Add(d)
+			heap.Push(&s.throttled, h)
+			continue
+		}
+		job := h.queue[0]
+		h.queue = h.queue[1:]
+		h.running++
+		s.queued--
+		s.update(h)
+		s.recount(h)
+		return job, 0, true
+	}
+
+	if s.throttled.Len() > 0 {
+		return Job{}, s.throttled.hosts[0].until.Sub(now), false
+	}
+	return Job{}, 0, false
+}
+
+// release frees the slot of the job on its host, and wakes the scheduler
+func (s *scheduler) release(job Job) {
+	s.mu.Lock()
+	h := s.hostOf[job.Seq%int64(len(s.hostOf))]
+	h.running--
+	s.update(h)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// countLow adds To the number of hosts running low of the feed of the host, or of the waiting hosts
+func (s *scheduler) countLow(h *host, delta int) {
+	if h.feed != nil {
+		h.feed.low += delta
 	} else {
-		for _, word := range words {
-			for _, url := range urls {
-				wg.Add(1)               // Increment the wait group counter
-				semaphore <- struct{}{} // acquire semaphore
-				go MakeRequest(url, word, wg, semaphore, ctx, config.Cfg, bar)
+		s.behind += delta
+	}
+}
+
+// recount records whether the queue of the host runs low, a host with all its jobs queued never does
+func (s *scheduler) recount(h *host) {
+	low := len(h.queue) < lowPerHost && h.combo < s.total
+	if low == h.low {
+		return
+	}
+	h.low = low
+	if low {
+		s.countLow(h, 1)
+	} else {
+		s.countLow(h, -1)
+	}
+}
+
+// setFeed moves the host To the feed, nil while it waits for one
+func (s *scheduler) setFeed(h *host, f *feed) {
+	if h.low {
+		s.countLow(h, -1)
+	}
+	h.feed = f
+	if h.low {
+		s.countLow(h, 1)
+	}
+}
+
+// park makes the host wait for a read of the wordlists at its position, it joins a feed already there
+func (s *scheduler) park(h *host) {
+	for _, f := range s.feeds {
+		if f.combo == h.combo {
+			s.setFeed(h, f)
+			f.hosts = append(f.hosts, h)
+			return
+		}
+	}
+	s.setFeed(h, nil)
+	s.waiting[h.combo] = append(s.waiting[h.combo], h)
+}
+
+// adopt moves the hosts waiting at the position of the feed To it
+func (s *scheduler) adopt(f *feed) {
+	for _, h := range s.waiting[f.combo] {
+		s.setFeed(h, f)
+		f.hosts = append(f.hosts, h)
+	}
+	delete(s.waiting, f.combo)
+}
+
+// open starts reads of the wordlists for the waiting hosts running low, each at the lowest position
+// of those hosts, the hosts waiting further on join it as it gets To them
+func (s *scheduler) open(ctx context.Context, wordlists []config.Wordlist) {
+	for s.behind > 0 && len(s.feeds) < maxFeeds {
+		start := int64(-1)
+		for combo, hosts := range s.waiting {
+			if start >= 0 && combo >= start {
+				continue
+			}
+			for _, h := range hosts {
+				if h.low {
+					start = combo
+					break
+				}
+			}
+		}
+
+		f := &feed{combo: start}
+		if len(wordlists) == 0 {
+			// Without wordlists each URL is requested once, as a single combination without inputs
+			single := make(chan map[string]string, 1)
+			single <- nil
+			close(single)
+			f.combinations, f.cancel = single, func() {}
+		} else {
+			var feedCtx context.Context
+			feedCtx, f.cancel = context.WithCancel(ctx)
+			f.combinations = opt.CombinationsFrom(feedCtx, wordlists, config.Cfg.Mode, start)
+		}
+		s.feeds = append(s.feeds, f)
+		s.adopt(f)
+	}
+}
+
+// close stops the read of the feed
+func (s *scheduler) close(f *feed) {
+	f.cancel()
+	s.feeds = slices.DeleteFunc(s.feeds, func(g *feed) bool { return g == f })
+}
+
+// queue queues the jobs of the combination read by the feed on its hosts, but for the jobs done before a
+// resume. A host with a full queue falls behind, the feed moves on without it.
+func (s *scheduler) queue(f *feed, inputs map[string]string) {
+	urls := int64(len(s.urls))
+	hosts := f.hosts
+	f.hosts = nil
+	f.combo++
+	for _, h := range hosts {
+		if len(h.queue) >= queuePerHost {
+			s.park(h)
+			continue
+		}
+		for _, url := range h.urls {
+			if h.combo >= s.progress[url] {
+				h.queue = append(h.queue, Job{Seq: h.combo*urls + url, URL: s.urls[url], Inputs: inputs})
+				s.queued++
+			}
+		}
+		h.combo++
+		s.update(h)
+		s.recount(h)
+		if h.combo < s.total {
+			f.hosts = append(f.hosts, h)
+		} else {
+			s.setFeed(h, nil)
+		}
+	}
+	s.adopt(f)
+
+	// A feed that caught up with another one hands its hosts over
+	for _, g := range s.feeds {
+		if g != f && g.combo == f.combo {
+			for _, h := range f.hosts {
+				s.setFeed(h, g)
+			}
+			g.hosts = append(g.hosts, f.hosts...)
+			f.hosts = nil
+			break
+		}
+	}
+	if len(f.hosts) == 0 {
+		s.close(f)
+	}
+}
+
+// fill reads the wordlists for the hosts running low, and opens new reads for the waiting ones
+func (s *scheduler) fill(ctx context.Context, wordlists []config.Wordlist) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.open(ctx, wordlists)
+	for i := 0; i < len(s.feeds); i++ {
+		f := s.feeds[i]
+		for f.low > 0 && len(f.hosts) > 0 {
+			// Let the workers release their hosts while the next combination is read
+			s.mu.Unlock()
+			inputs, ok := <-f.combinations
+			s.mu.Lock()
+			if !ok {
+				if ctx.Err() != nil {
+					return
+				}
+				// The wordlists ended early, there is nothing more To queue for the hosts
+				for _, h := range f.hosts {
+					h.combo = s.total
+					s.recount(h)
+					s.setFeed(h, nil)
+				}
+				f.hosts = nil
+				s.close(f)
+				break
+			}
+			s.queue(f, inputs)
+		}
+	}
+}
+
+// done reports whether every job was handed out
+func (s *scheduler) done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queued == 0 && len(s.feeds) == 0 && len(s.waiting) == 0
+}
+
+// wait blocks until a request is done, the given time has passed (0 is no timeout) or the context is canceled
+func (s *scheduler) wait(ctx context.Context, timeout time.Duration) {
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+	select {
+	case <-s.wake:
+	case <-timer:
+	case <-ctx.Done():
+	}
+}
+
+// startRequests starts the HTTP requests on a fixed pool of workers, and returns once they are all done.
+// The wordlists are read as the hosts need jobs and each job is queued on its host, the workers get
+// the jobs in seq order as long as their host has a free slot and token, so a slow host only holds
+// its own requests back. Jobs done before a resume are skipped, so that a resumed scan continues where
+// each URL stopped.
+func StartRequests(ctx context.Context, bar *progressbar.ProgressBar, tracker *Tracker, wordlists []config.Wordlist, urls []string) {
+	request := MakeRequest
+	if config.Cfg.WebCache && len(wordlists) == 0 {
+		request = WebCacheRequest
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	total := int64(1)
+	if len(wordlists) > 0 {
+		total = int64(opt.CountCombinations(wordlists, config.Cfg.Mode))
+	}
+	s := newScheduler(urls, tracker.Progress(), total)
+
+	// Use a WaitGroup To wait for all workers To finish
+	var wg sync.WaitGroup
+	jobs := make(chan Job)
+	for i := 0; i < config.Cfg.Concurrency; i++ {
+		wg.Add(1)
+		go worker(ctx, &wg, jobs, bar, tracker, s, request)
+	}
+
+	for ctx.Err() == nil {
+		// Queue the jobs of the hosts running low
+		s.fill(ctx, wordlists)
+
+		job, wait, ok := s.next()
+		if ok {
+			// Take the token of the -rate bucket here, so the jobs get them in seq order
+			if Limiter.Wait(ctx) != nil {
+				continue
+			}
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
 			}
+			continue
+		}
+		if s.done() {
+			break
+		}
+		// Wait for a request To be done, or for a host To get a token
+		s.wait(ctx, wait)
+	}
+	close(jobs)
+
+	wg.Wait()
+}
+
+// worker makes the requests for the jobs it pulls from the queue
+func worker(ctx context.Context, wg *sync.WaitGroup, jobs <-chan Job, bar *progressbar.ProgressBar, tracker *Tracker, s *scheduler, request func(context.Context, Job)) {
+	defer wg.Done()
+	for job := range jobs {
+		// Drain the queued jobs without sending them once the context is canceled
+		if ctx.Err() == nil {
+			request(ctx, job)
+			tracker.Done(job.Seq)
+			bar.Add(1)
 		}
+		s.release(job)
 	}
 }
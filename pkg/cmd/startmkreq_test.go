@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/schollz/progressbar/v3"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/matcher"
+	"github.com/SpeedyQweku/qfuzz/pkg/opt"
+)
+
+// benchWords is the number of requests of each benchmark run
+const benchWords = 1000
+
+// writeWords writes a wordlist of n words, and returns it bound To FUZZ
+func writeWords(tb testing.TB, n int) []config.Wordlist {
+	path := filepath.Join(tb.TempDir(), "words.txt")
+	file, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(file, "w%d\n", i)
+	}
+	file.Close()
+	return []config.Wordlist{{Path: path, Keyword: "FUZZ", Count: n}}
+}
+
+// setupBenchmark starts a local server and returns a wordlist and its target URL, nothing is matched
+func setupBenchmark(b *testing.B) ([]config.Wordlist, []string) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	b.Cleanup(server.Close)
+
+	var err error
+	config.Cfg.Concurrency = 40
+	config.Cfg.Mode = config.ModeClusterbomb
+	config.Cfg.MatchMode = matcher.ModeAnd
+	config.Cfg.FilterMode = matcher.ModeOr
+	config.Cfg.MatchScope = matcher.ScopeBody
+	config.Cfg.MatchStatus = []string{"599"}
+	config.Cfg.DedupeMax = 1
+	if opt.Matchers, err = matcher.New(&config.Cfg); err != nil {
+		b.Fatal(err)
+	}
+	return writeWords(b, benchWords), []string{server.URL + "/FUZZ"}
+}
+
+// push queues the job on its host, as a feed does
+func (s *scheduler) push(job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.hostOf[job.Seq%int64(len(s.hostOf))]
+	h.queue = append(h.queue, job)
+	s.queued++
+	s.update(h)
+}
+
+// schedule hands out the jobs of the scheduler, the jobs of the URLs in hold are not completed
+// until none of the others can go. It returns the jobs in the order they were handed out.
+func schedule(t *testing.T, s *scheduler, wordlists []config.Wordlist, tracker *Tracker, hold map[string]bool) []Job {
+	ctx := context.Background()
+	var sent, held []Job
+	for {
+		s.fill(ctx, wordlists)
+		for _, h := range s.hosts {
+			if len(h.queue) > queuePerHost+len(h.urls) {
+				t.Fatalf("%d jobs queued on a host, want at most %d", len(h.queue), queuePerHost+len(h.urls))
+			}
+		}
+
+		job, _, ok := s.next()
+		if !ok {
+			if len(held) == 0 {
+				break
+			}
+			for _, job := range held {
+				tracker.Done(job.Seq)
+				s.release(job)
+			}
+			held = nil
+			continue
+		}
+		sent = append(sent, job)
+		if hold[job.URL] {
+			held = append(held, job)
+			continue
+		}
+		tracker.Done(job.Seq)
+		s.release(job)
+	}
+	if !s.done() {
+		t.Fatal("jobs left once the scheduler has none To hand out")
+	}
+	return sent
+}
+
+// byURL groups the seqs of the jobs by URL, in order
+func byURL(jobs []Job) map[string][]int64 {
+	seqs := make(map[string][]int64)
+	for _, job := range jobs {
+		seqs[job.URL] = append(seqs[job.URL], job.Seq)
+	}
+	return seqs
+}
+
+func TestSchedulerSlowHost(t *testing.T) {
+	const words = 1000
+	config.Cfg.Mode = config.ModeClusterbomb
+	config.Cfg.HostConcurrency = 1
+	t.Cleanup(func() { config.Cfg = config.Config{} })
+
+	wordlists := writeWords(t, words)
+	urls := []string{"https://slow.example.com/FUZZ", "https://fast.example.com/FUZZ", "https://fast.example.com/a/FUZZ"}
+	s := newScheduler(urls, nil, words)
+	tracker := NewTracker(len(urls), nil)
+
+	// The request To the slow host does not end while the fast host has jobs left, the fast host gets them all first
+	jobs := schedule(t, s, wordlists, tracker, map[string]bool{urls[0]: true})
+	for i, job := range jobs[:2*words+1] {
+		if job.URL == urls[0] && i > 0 {
+			t.Fatalf("job %d is %d of the slow host, want the fast host's jobs first", i, job.Seq)
+		}
+	}
+
+	sent := byURL(jobs)
+	for i, url := range urls {
+		if len(sent[url]) != words {
+			t.Errorf("%s got %d jobs, want %d", url, len(sent[url]), words)
+			continue
+		}
+		for combo, seq := range sent[url] {
+			if want := int64(combo*len(urls) + i); seq != want {
+				t.Errorf("%s job %d is %d, want %d", url, combo, seq, want)
+				break
+			}
+		}
+	}
+	if progress := tracker.Progress(); !slices.Equal(progress, []int64{words, words, words}) {
+		t.Errorf("progress = %v, want all %d", progress, words)
+	}
+}
+
+func TestSchedulerResume(t *testing.T) {
+	const words = 300
+	config.Cfg.Mode = config.ModeClusterbomb
+	t.Cleanup(func() { config.Cfg = config.Config{} })
+
+	// The URLs of a host may have stopped at different places, a done URL gets nothing
+	wordlists := writeWords(t, words)
+	urls := []string{"https://a.example.com/FUZZ", "https://a.example.com/b/FUZZ", "https://c.example.com/FUZZ"}
+	progress := []int64{10, 250, words}
+	s := newScheduler(urls, progress, words)
+	tracker := NewTracker(len(urls), progress)
+
+	sent := byURL(schedule(t, s, wordlists, tracker, nil))
+	for i, url := range urls {
+		if got, want := len(sent[url]), words-int(progress[i]); got != want {
+			t.Errorf("%s got %d jobs, want %d", url, got, want)
+		} else if got > 0 && sent[url][0] != progress[i]*int64(len(urls))+int64(i) {
+			t.Errorf("%s starts at job %d, want combination %d", url, sent[url][0], progress[i])
+		}
+	}
+	if got := tracker.Progress(); !slices.Equal(got, []int64{words, words, words}) {
+		t.Errorf("progress = %v, want all %d", got, words)
+	}
+}
+
+// startRequestsPerGoroutine is the scheduling the worker pool replaced, a goroutine per request behind a semaphore
+func startRequestsPerGoroutine(ctx context.Context, bar *progressbar.ProgressBar, wordlists []config.Wordlist, urls []string) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, config.Cfg.Concurrency)
+	var seq int64
+	for inputs := range opt.Combinations(ctx, wordlists, config.Cfg.Mode) {
+		for _, url := range urls {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(job Job) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				MakeRequest(ctx, job)
+				bar.Add(1)
+			}(Job{Seq: seq, URL: url, Inputs: inputs})
+			seq++
+		}
+	}
+	wg.Wait()
+}
+
+func BenchmarkStartRequests(b *testing.B) {
+	wordlists, urls := setupBenchmark(b)
+	ctx := context.Background()
+
+	b.Run("pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			bar := progressbar.NewOptions(benchWords, progressbar.OptionSetWriter(io.Discard))
+			StartRequests(ctx, bar, NewTracker(len(urls), nil), wordlists, urls)
+		}
+	})
+	b.Run("goroutine-per-request", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			bar := progressbar.NewOptions(benchWords, progressbar.OptionSetWriter(io.Discard))
+			startRequestsPerGoroutine(ctx, bar, wordlists, urls)
+		}
+	})
+}
+
+// benchHosts is the number of hosts of the scheduler benchmark
+const benchHosts = 10000
+
+// nextSorted is the lookup the ready heap replaced, the hosts sorted by their first job on every call
+func (s *scheduler) nextSorted() (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ready []*host
+	for _, h := range s.hosts {
+		if len(h.queue) > 0 && (config.Cfg.HostConcurrency <= 0 || h.running < config.Cfg.HostConcurrency) {
+			ready = append(ready, h)
+		}
+	}
+	slices.SortFunc(ready, func(a, b *host) int { return cmp.Compare(a.queue[0].Seq, b.queue[0].Seq) })
+	for _, h := range ready {
+		if h.limiter.Take() > 0 {
+			continue
+		}
+		job := h.queue[0]
+		h.queue = h.queue[1:]
+		h.running++
+		s.queued--
+		return job, true
+	}
+	return Job{}, false
+}
+
+func BenchmarkScheduler(b *testing.B) {
+	config.Cfg.HostConcurrency = 2
+	b.Cleanup(func() { config.Cfg.HostConcurrency = 0 })
+	urls := make([]string, benchHosts)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://host%d.example.com/FUZZ", i)
+	}
+
+	// Every host has a few jobs queued, each done job is replaced by the next one of its host
+	setup := func() *scheduler {
+		s := newScheduler(urls, nil, 0)
+		for seq := int64(0); seq < 4*benchHosts; seq++ {
+			s.push(Job{Seq: seq, URL: urls[seq%benchHosts]})
+		}
+		return s
+	}
+
+	b.Run("heap", func(b *testing.B) {
+		s := setup()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			job, _, ok := s.next()
+			if !ok {
+				b.Fatal("no job")
+			}
+			s.release(job)
+			s.push(Job{Seq: job.Seq + 4*benchHosts, URL: job.URL})
+		}
+	})
+	b.Run("sort", func(b *testing.B) {
+		s := setup()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			job, ok := s.nextSorted()
+			if !ok {
+				b.Fatal("no job")
+			}
+			s.release(job)
+			s.push(Job{Seq: job.Seq + 4*benchHosts, URL: job.URL})
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/projectdiscovery/goflags"
@@ -13,6 +14,7 @@ import (
 
 const (
 	Version = "v1.0.2"
+	Keyword = "FUZZ" // Keyword is the default placeholder for wordlists without an explicit keyword.
 	Reset   = "\033[0m"
 	Red     = "\033[31m"
 	Blue    = "\033[34m"
@@ -32,46 +34,110 @@ const (
 
 // Result represents the result of an HTTP request.
 type Result struct {
-	StatusCode  int           // StatusCode is the HTTP status code returned by the server (e.g., 200, 404).
-	Status      string        // Status is the HTTP status message returned by the server (e.g., "200 OK", "404 Not Found").
-	ContentSize int64         // ContentSize is the size of the response content in bytes.
-	URL         string        // URL is the URL that was requested.
-	Match       bool          // Match indicates whether the response matched certain criteria (specific strings).
-	Ttaken      time.Duration // Ttaken is the time taken to complete the request (Millisecond).
+	StatusCode       int               `json:"status"`                      // StatusCode is the HTTP status code returned by the server (e.g., 200, 404).
+	Status           string            `json:"-"`                           // Status is the HTTP status message returned by the server (e.g., "200 OK", "404 Not Found").
+	ContentSize      int64             `json:"length"`                      // ContentSize is the size of the response content in bytes.
+	URL              string            `json:"url"`                         // URL is the URL that was requested.
+	Input            map[string]string `json:"input,omitempty"`             // Input maps each keyword To the word used in the request.
+	Method           string            `json:"method"`                      // Method is the HTTP method of the request.
+	Words            int               `json:"words"`                       // Words is the number of words in the response body.
+	Lines            int               `json:"lines"`                       // Lines is the number of lines in the response body.
+	RedirectLocation string            `json:"redirect_location,omitempty"` // RedirectLocation is the Location header of the response.
+	ContentType      string            `json:"content_type,omitempty"`      // ContentType is the Content-Type header of the response.
+	Captures         map[string]string `json:"captures,omitempty"`          // Captures maps the named groups of the -mr regexes To the matched values.
+	Cluster          int               `json:"cluster,omitempty"`           // Cluster is the similarity cluster of the response with -dedupe-threshold.
+	Ttaken           time.Duration     `json:"-"`                           // Ttaken is the time taken to complete the request.
+	DurationMs       int64             `json:"duration_ms"`                 // DurationMs is Ttaken in milliseconds, as saved in the output files.
+	Timestamp        time.Time         `json:"timestamp"`                   // Timestamp is when the response was received.
+	Job              int64             `json:"-"`                           // Job is the position of the request in the run, used To resume scans.
+}
+
+// Wordlist modes, deciding how words from multiple wordlists are combined.
+const (
+	ModeClusterbomb = "clusterbomb" // ModeClusterbomb tries every combination of words across the wordlists.
+	ModePitchfork   = "pitchfork"   // ModePitchfork iterates the wordlists in lock-step.
+	ModeSniper      = "sniper"      // ModeSniper fuzzes one keyword at a time, leaving the others at their default.
+)
+
+// Wordlist represents a wordlist file bound To a keyword placeholder.
+type Wordlist struct {
+	Path    string // Path is the path To the wordlist file.
+	Keyword string // Keyword is the placeholder replaced by each word (e.g., FUZZ, USER).
+	Count   int    // Count is the number of words in the wordlist file.
 }
 
 // Config holds configuration settings for the application.
 type Config struct {
 	OutputFile        string              // OutputFile specifies the path to the file where output will be written.
-	WordlistFile      string              // WordlistFile specifies the path to the file containing a list of words.
+	OutputFormat      string              // OutputFormat specifies the format of the output file (text, json, jsonl, csv, html).
 	UrlFile           string              // UrlFile specifies the path to the file containing a list of URLs.
+	RequestFile       string              // RequestFile specifies the path to a raw HTTP request file to fuzz.
+	RequestProto      string              // RequestProto specifies the scheme used with the raw HTTP request file (e.g., https).
 	PostData          string              // PostData contains the data to be sent in a POST request.
 	HttpMethod        string              // HttpMethod specifies the HTTP method to use (e.g., GET, POST).
-	UserAgents        []string            // UserAgents is a list of user agent strings to use for requests.
+	Mode              string              // Mode specifies how words from multiple wordlists are combined (clusterbomb, pitchfork, sniper).
+	MatchMode         string              // MatchMode specifies how the matchers are combined (and, or).
+	FilterMode        string              // FilterMode specifies how the filters are combined (and, or).
+	MatchScope        string              // MatchScope specifies the part of the response the strings are matched in (text, title, body, headers, all).
+	MatchExpr         string              // MatchExpr specifies an expression the responses must match (e.g., status == 200 && size > 1000).
+	UserAgents        []string            `json:"-"` // UserAgents is a list of user agent strings to use for requests.
 	FollowRedirect    bool                // FollowRedirect indicates whether redirects should be followed.
 	Silent            bool                // Silent controls whether output should be minimized.
 	RandomUserAgent   bool                // RandomUserAgent indicates whether a random user agent should be used for each request.
 	Debug             bool                // Debug enables debug mode, providing more detailed logging.
 	Http2             bool                // Http2 enables the use of HTTP/2 for requests.
+	AutoCalibration   bool                // AutoCalibration indicates whether responses matching the calibration baselines are filtered.
 	WebCache          bool                // WebCache enables the use of web caching detection.
 	To                int                 // To specifies the timeout for HTTP requests, in seconds.
 	Concurrency       int                 // Concurrency specifies the number of concurrent requests to make.
-	Retries           int                 // Retries specifies the number of times to retry failed requests.
-	SuccessFile       *os.File            // SuccessFile is a file handle to write successful requests to.
-	Cachefile         *os.File            // Cachefile is a file handle to write successful web caching detection.
+	Rate              int                 // Rate specifies the maximum number of requests per second, 0 for no limit.
+	HostConcurrency   int                 // HostConcurrency specifies the maximum number of concurrent requests per host, 0 for no limit.
+	HostRate          int                 // HostRate specifies the maximum number of requests per second per host, 0 for no limit.
+	Retries           int                 // Retries specifies the number of times to retry the requests answered with a -retry-codes status.
+	RetryMaxWait      int                 // RetryMaxWait specifies the longest wait before a retry, in seconds.
+	DedupeThreshold   float64             // DedupeThreshold specifies the body similarity above which responses are clustered, 0 disables it.
+	DedupeMax         int                 // DedupeMax specifies the number of responses reported per similarity cluster.
+	SaveState         string              // SaveState specifies the path of the state file saved periodically and on interrupt.
+	Resume            string              // Resume specifies the path of a state file to resume a scan from.
+	ProxyList         string              // ProxyList specifies the path to a file of proxy URLs, rotated with the -proxy ones.
+	ReplayProxy       string              // ReplayProxy specifies the proxy URL the matched requests are sent again through.
+	SuccessFile       *os.File            `json:"-"` // SuccessFile is a file handle to write successful requests to.
+	Cachefile         *os.File            `json:"-"` // Cachefile is a file handle to write successful web caching detection.
+	Wordlists         goflags.StringSlice // Wordlists is a slice of wordlist file paths, optionally bound To a keyword (path:KEYWORD).
 	UrlString         goflags.StringSlice // UrlString is a slice of URL strings specified.
 	Headers           goflags.StringSlice // Headers is a slice of HTTP headers specified.
+	RetryCodes        goflags.StringSlice // RetryCodes is a slice of HTTP status codes the requests are retried on (e.g., 429, 503).
+	Proxy             goflags.StringSlice // Proxy is a slice of proxy URLs (http, https, socks5), rotated per request.
 	MatchStrings      goflags.StringSlice // MatchStrings is a slice of strings to match in responses.
+	MatchRegex        goflags.StringSlice // MatchRegex is a slice of regular expressions to match in response bodies and headers.
+	MatchHeaders      goflags.StringSlice // MatchHeaders is a slice of headers to match in responses (Name, Name: value or Name: /regex/).
 	MatchStatus       goflags.StringSlice // MatchStatus is a slice of HTTP status codes to match in responses status code.
+	MatchWords        goflags.StringSlice // MatchWords is a slice of word counts to match in response bodies.
+	MatchLines        goflags.StringSlice // MatchLines is a slice of line counts to match in response bodies.
+	MatchTime         goflags.StringSlice // MatchTime is a slice of response time comparisons to match, in milliseconds (e.g., >5000).
 	MatchContentSize  goflags.StringSlice // MatchContentSize is a slice of ContentSize to match in Content-Length.
 	FilterStrings     goflags.StringSlice // FilterStrings is a slice of strings to filter out in responses.
+	FilterRegex       goflags.StringSlice // FilterRegex is a slice of regular expressions to filter out in response bodies and headers.
+	FilterHeaders     goflags.StringSlice // FilterHeaders is a slice of headers to filter out in responses (Name, Name: value or Name: /regex/).
 	FilterStatus      goflags.StringSlice // FilterStatus is a slice of HTTP status codes to filter out in responses status code.
+	FilterWords       goflags.StringSlice // FilterWords is a slice of word counts to filter out in response bodies.
+	FilterLines       goflags.StringSlice // FilterLines is a slice of line counts to filter out in response bodies.
+	CustomCalibration goflags.StringSlice // CustomCalibration is a slice of custom strings sent as calibration requests.
+	FilterTime        goflags.StringSlice // FilterTime is a slice of response time comparisons to filter out, in milliseconds (e.g., <100).
 	FilterContentSize goflags.StringSlice // FilterContentSize is a slice of ContentSize to filter out in Content-Length.
 }
 
+// Stats holds the counters reported in the summary at the end of a run.
+type Stats struct {
+	Requests atomic.Int64 // Requests is the number of requests sent.
+	Errors   atomic.Int64 // Errors is the number of requests that failed without a response.
+	Matches  atomic.Int64 // Matches is the number of results printed and saved.
+}
+
 var (
-	Cfg Config
-	Mu  sync.Mutex
+	Cfg  Config
+	Mu   sync.Mutex
+	Stat Stats
 )
 
 var HttpClient = &http.Client{
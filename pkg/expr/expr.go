@@ -0,0 +1,399 @@
+// Package expr implements the small expression language of -match-expr, such as
+// status == 200 && size > 1000 && !contains(body, "Not Found")
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Type is the type of a value of an expression
+type Type int
+
+const (
+	Int    Type = iota // Int is a whole number, such as a status code or a size.
+	String             // String is a text, such as the body or a header value.
+	Bool               // Bool is the result of a comparison or a helper function.
+)
+
+func (t Type) String() string {
+	switch t {
+	case Int:
+		return "number"
+	case String:
+		return "string"
+	default:
+		return "bool"
+	}
+}
+
+// Vars are the variables of the language and their type
+var Vars = map[string]Type{
+	"status":       Int,    // status is the status code.
+	"size":         Int,    // size is the content size in bytes.
+	"words":        Int,    // words is the number of words in the body.
+	"lines":        Int,    // lines is the number of lines in the body.
+	"duration":     Int,    // duration is the response time in milliseconds.
+	"body":         String, // body is the raw body.
+	"headers":      String, // headers are the "Name: value" header lines.
+	"title":        String, // title is the title of the HTML body.
+	"url":          String, // url is the requested URL.
+	"method":       String, // method is the HTTP method.
+	"content_type": String, // content_type is the Content-Type header.
+	"redirect":     String, // redirect is the Location header.
+}
+
+// Env gives the values of the variables for a response
+type Env interface {
+	Int(name string) int64
+	String(name string) string
+	Header(name string) (string, bool) // Header returns the first value of a header, and whether it is present.
+}
+
+// node is a compiled expression, only the function of its type is set
+type node struct {
+	typ  Type
+	pos  int
+	lit  *string // lit is the value of a string literal, nil for other nodes.
+	num  func(Env) int64
+	str  func(Env) string
+	bool func(Env) bool
+}
+
+// function is a helper function of the language
+type function struct {
+	args  []Type
+	build func(args []*node) (*node, error)
+}
+
+// functions are the helper functions of the language
+var functions = map[string]function{
+	"contains": {[]Type{String, String}, func(args []*node) (*node, error) {
+		return strFunc2(args, strings.Contains), nil
+	}},
+	"icontains": {[]Type{String, String}, func(args []*node) (*node, error) {
+		return strFunc2(args, func(s, sub string) bool { return strings.Contains(strings.ToLower(s), strings.ToLower(sub)) }), nil
+	}},
+	"starts_with": {[]Type{String, String}, func(args []*node) (*node, error) {
+		return strFunc2(args, strings.HasPrefix), nil
+	}},
+	"ends_with": {[]Type{String, String}, func(args []*node) (*node, error) {
+		return strFunc2(args, strings.HasSuffix), nil
+	}},
+	"matches": {[]Type{String, String}, func(args []*node) (*node, error) {
+		if args[1].lit == nil {
+			return nil, fmt.Errorf("the regex of matches must be a string at %d", args[1].pos)
+		}
+		re, err := regexp.Compile(*args[1].lit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex at %d: %v", args[1].pos, err)
+		}
+		s := args[0].str
+		return &node{typ: Bool, bool: func(env Env) bool { return re.MatchString(s(env)) }}, nil
+	}},
+	"header": {[]Type{String}, func(args []*node) (*node, error) {
+		name := args[0].str
+		return &node{typ: String, str: func(env Env) string {
+			value, _ := env.Header(name(env))
+			return value
+		}}, nil
+	}},
+	"has_header": {[]Type{String}, func(args []*node) (*node, error) {
+		name := args[0].str
+		return &node{typ: Bool, bool: func(env Env) bool {
+			_, ok := env.Header(name(env))
+			return ok
+		}}, nil
+	}},
+	"len": {[]Type{String}, func(args []*node) (*node, error) {
+		s := args[0].str
+		return &node{typ: Int, num: func(env Env) int64 { return int64(len(s(env))) }}, nil
+	}},
+	"lower": {[]Type{String}, func(args []*node) (*node, error) {
+		s := args[0].str
+		return &node{typ: String, str: func(env Env) string { return strings.ToLower(s(env)) }}, nil
+	}},
+}
+
+// strFunc2 returns a bool node calling f with two strings
+func strFunc2(args []*node, f func(string, string) bool) *node {
+	a, b := args[0].str, args[1].str
+	return &node{typ: Bool, bool: func(env Env) bool { return f(a(env), b(env)) }}
+}
+
+// Expr is a compiled expression
+type Expr struct {
+	Source string // Source is the expression as given.
+
+	root *node
+}
+
+// Compile parses and type checks an expression, it must be a condition
+func Compile(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at %d", tok.text, tok.pos)
+	}
+	if root.typ != Bool {
+		return nil, fmt.Errorf("the expression is a %s, not a condition", root.typ)
+	}
+	return &Expr{Source: src, root: root}, nil
+}
+
+// Eval reports whether the expression is true for the environment
+func (e *Expr) Eval(env Env) bool {
+	return e.root.bool(env)
+}
+
+// parser builds the nodes of an expression, from the lowest precedence:
+// ||, &&, !, comparisons, then values, function calls and parentheses
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+// expect reads a token of the kind, or returns an error naming what was expected
+func (p *parser) expect(kind tokenKind, what string) error {
+	if tok := p.next(); tok.kind != kind {
+		return fmt.Errorf("expected %s at %d", what, tok.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (*node, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	return p.parseLogical("&&", p.parseUnary)
+}
+
+// parseLogical parses operands joined by a logical operator, the right operand is
+// only evaluated when needed
+func (p *parser) parseLogical(op string, operand func() (*node, error)) (*node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOp && p.peek().text == op {
+		tok := p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ != Bool || right.typ != Bool {
+			return nil, fmt.Errorf("%s needs conditions at %d, got %s and %s", op, tok.pos, left.typ, right.typ)
+		}
+		a, b := left.bool, right.bool
+		if op == "||" {
+			left = &node{typ: Bool, pos: left.pos, bool: func(env Env) bool { return a(env) || b(env) }}
+		} else {
+			left = &node{typ: Bool, pos: left.pos, bool: func(env Env) bool { return a(env) && b(env) }}
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (*node, error) {
+	if tok := p.peek(); tok.kind == tokenOp && tok.text == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if operand.typ != Bool {
+			return nil, fmt.Errorf("! needs a condition at %d, got %s", tok.pos, operand.typ)
+		}
+		b := operand.bool
+		return &node{typ: Bool, pos: tok.pos, bool: func(env Env) bool { return !b(env) }}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (*node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	if tok.kind != tokenOp || tok.text == "&&" || tok.text == "||" || tok.text == "!" {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if left.typ != right.typ {
+		return nil, fmt.Errorf("cannot compare %s and %s with %s at %d", left.typ, right.typ, tok.text, tok.pos)
+	}
+
+	switch left.typ {
+	case Int:
+		a, b := left.num, right.num
+		cmp := func(env Env) int {
+			x, y := a(env), b(env)
+			if x < y {
+				return -1
+			} else if x > y {
+				return 1
+			}
+			return 0
+		}
+		return compare(tok, left.pos, cmp)
+	case String:
+		a, b := left.str, right.str
+		return compare(tok, left.pos, func(env Env) int { return strings.Compare(a(env), b(env)) })
+	default:
+		if tok.text != "==" && tok.text != "!=" {
+			return nil, fmt.Errorf("cannot compare conditions with %s at %d", tok.text, tok.pos)
+		}
+		a, b := left.bool, right.bool
+		eq := tok.text == "=="
+		return &node{typ: Bool, pos: left.pos, bool: func(env Env) bool { return (a(env) == b(env)) == eq }}, nil
+	}
+}
+
+// compare returns the node of a comparison operator, cmp returns -1, 0 or 1
+func compare(op token, pos int, cmp func(Env) int) (*node, error) {
+	var test func(int) bool
+	switch op.text {
+	case "==":
+		test = func(c int) bool { return c == 0 }
+	case "!=":
+		test = func(c int) bool { return c != 0 }
+	case "<":
+		test = func(c int) bool { return c < 0 }
+	case "<=":
+		test = func(c int) bool { return c <= 0 }
+	case ">":
+		test = func(c int) bool { return c > 0 }
+	case ">=":
+		test = func(c int) bool { return c >= 0 }
+	default:
+		return nil, fmt.Errorf("unexpected %s at %d", op.text, op.pos)
+	}
+	return &node{typ: Bool, pos: pos, bool: func(env Env) bool { return test(cmp(env)) }}, nil
+}
+
+func (p *parser) parsePrimary() (*node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		n := tok.num
+		return &node{typ: Int, pos: tok.pos, num: func(Env) int64 { return n }}, nil
+	case tokenString:
+		s := tok.text
+		return &node{typ: String, pos: tok.pos, lit: &s, str: func(Env) string { return s }}, nil
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case tokenIdent:
+		if p.peek().kind == tokenLParen {
+			return p.parseCall(tok)
+		}
+		return variable(tok)
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression at %d", tok.pos)
+	default:
+		return nil, fmt.Errorf("unexpected %s at %d", tok.text, tok.pos)
+	}
+}
+
+// variable returns the node of a variable or a boolean constant
+func variable(tok token) (*node, error) {
+	switch tok.text {
+	case "true", "false":
+		b := tok.text == "true"
+		return &node{typ: Bool, pos: tok.pos, bool: func(Env) bool { return b }}, nil
+	}
+	typ, ok := Vars[tok.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %s at %d (%s)", tok.text, tok.pos, names(Vars))
+	}
+	name := tok.text
+	if typ == Int {
+		return &node{typ: Int, pos: tok.pos, num: func(env Env) int64 { return env.Int(name) }}, nil
+	}
+	return &node{typ: String, pos: tok.pos, str: func(env Env) string { return env.String(name) }}, nil
+}
+
+// parseCall parses the arguments of a helper function and checks their types
+func (p *parser) parseCall(name token) (*node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s at %d (%s)", name.text, name.pos, names(functions))
+	}
+	p.next()
+
+	var args []*node
+	if p.peek().kind != tokenRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expect(tokenRParen, ")"); err != nil {
+		return nil, err
+	}
+
+	if len(args) != len(fn.args) {
+		return nil, fmt.Errorf("%s takes %d arguments at %d, got %d", name.text, len(fn.args), name.pos, len(args))
+	}
+	for i, arg := range args {
+		if arg.typ != fn.args[i] {
+			return nil, fmt.Errorf("argument %d of %s must be a %s at %d, got %s", i+1, name.text, fn.args[i], arg.pos, arg.typ)
+		}
+	}
+	n, err := fn.build(args)
+	if err != nil {
+		return nil, err
+	}
+	n.pos = name.pos
+	return n, nil
+}
+
+// names returns the sorted keys of a map, for error messages
+func names[T any](m map[string]T) string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,111 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+// env is a fixed environment, for a 200 response of an admin page
+type env struct{}
+
+func (env) Int(name string) int64 {
+	return map[string]int64{"status": 200, "size": 1234, "words": 56, "lines": 7, "duration": 89}[name]
+}
+
+func (env) String(name string) string {
+	return map[string]string{
+		"body":         `<title>Admin</title> Welcome "root"`,
+		"headers":      "Content-Type: text/html\nServer: nginx\n",
+		"title":        "Admin",
+		"url":          "https://example.com/admin",
+		"method":       "GET",
+		"content_type": "text/html",
+	}[name]
+}
+
+func (env) Header(name string) (string, bool) {
+	value, ok := map[string]string{"Server": "nginx", "X-Empty": ""}[name]
+	return value, ok
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"status == 200", true},
+		{"status != 200", false},
+		{"size > 1000 && size <= 1234", true},
+		{"words < 56 || lines >= 7", true},
+		{"duration > 100", false},
+		{"!(status == 404)", true},
+		{"!!true", true},
+		{"false || status == 200 && size == 0", false},
+		{"(false || status == 200) && size > 0", true},
+		{"true == (status == 200)", true},
+		{`title == "Admin"`, true},
+		{`method != 'POST'`, true},
+		{`url > "https://example.com/"`, true},
+		{`contains(body, "Welcome")`, true},
+		{`contains(body, "welcome")`, false},
+		{`icontains(body, "WELCOME")`, true},
+		{`contains(body, "\"root\"")`, true},
+		{`starts_with(url, "https://") && ends_with(url, "/admin")`, true},
+		{`matches(body, "<title>\w+</title>")`, true},
+		{`matches(content_type, "^application/")`, false},
+		{`header("Server") == "nginx"`, true},
+		{`header("Missing") == ""`, true},
+		{`has_header("X-Empty") && !has_header("Missing")`, true},
+		{`len(title) == 5`, true},
+		{`lower(title) == "admin"`, true},
+		{`contains(headers, "Server: nginx")`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Compile(%q) error = %v", tt.src, err)
+			}
+			if got := e.Eval(env{}); got != tt.want {
+				t.Errorf("Eval(%q) = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string // want is a part of the error message
+	}{
+		{"", "unexpected end of expression at 0"},
+		{"status", "not a condition"},
+		{"status ==", "unexpected end of expression"},
+		{"status == 200 200", "unexpected 200 at 14"},
+		{"(status == 200", "expected ) at 14"},
+		{"code == 200", "unknown variable code at 0"},
+		{"status == \"200\"", "cannot compare number and string"},
+		{"status && size", "&& needs conditions"},
+		{"!status", "! needs a condition"},
+		{"true < false", "cannot compare conditions with <"},
+		{"status = 200", "unexpected character '='"},
+		{`title == "Admin`, "unterminated string at 9"},
+		{"99999999999999999999 > 0", "invalid number"},
+		{`foo(body)`, "unknown function foo"},
+		{`contains(body)`, "contains takes 2 arguments"},
+		{`contains(body, 1)`, "argument 2 of contains must be a string"},
+		{`matches(body, title)`, "the regex of matches must be a string"},
+		{`matches(body, "(")`, "invalid regex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			if err == nil {
+				t.Fatalf("Compile(%q) returned no error, want %q", tt.src, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compile(%q) error = %q, want %q", tt.src, err, tt.want)
+			}
+		})
+	}
+}
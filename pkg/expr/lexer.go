@@ -0,0 +1,125 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of a token of an expression
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+// token is a single token of an expression, pos is its offset in the source
+type token struct {
+	kind tokenKind
+	text string
+	num  int64
+	pos  int
+}
+
+// operators are the operators of the language, longest first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!"}
+
+// lex splits the source into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			str, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: str, pos: i})
+			i = end
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+				i++
+			}
+			n, err := strconv.ParseInt(src[start:i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s at %d", src[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:i], num: n, pos: start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// lexString reads the quoted string starting at i, it returns the string and the offset after it.
+// A backslash escapes the quotes and itself, \n and \t are a newline and a tab, other
+// escapes are kept as is so regexes like "\d+" can be written without doubling the backslash.
+func lexString(src string, i int) (string, int, error) {
+	quote := src[i]
+	var str strings.Builder
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case quote:
+			return str.String(), j + 1, nil
+		case '\\':
+			j++
+			if j == len(src) {
+				break
+			}
+			switch src[j] {
+			case 'n':
+				str.WriteByte('\n')
+			case 't':
+				str.WriteByte('\t')
+			case '"', '\'', '\\':
+				str.WriteByte(src[j])
+			default:
+				str.WriteByte('\\')
+				str.WriteByte(src[j])
+			}
+		default:
+			str.WriteByte(src[j])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at %d", i)
+}
@@ -0,0 +1,167 @@
+package matcher
+
+import (
+	"fmt"
+	neturl "net/url"
+	"sort"
+	"strings"
+)
+
+// Fingerprint is the baseline of the calibration responses of a target, the counts that
+// differ between the calibration responses are left out
+type Fingerprint struct {
+	Status   int    // Status is the status code of the calibration responses.
+	Size     *int64 // Size is the content size, nil if it differs.
+	Words    *int64 // Words is the number of words, nil if it differs.
+	Lines    *int64 // Lines is the number of lines, nil if it differs.
+	Redirect string // Redirect is the redirect target, with the inputs replaced by their keyword.
+}
+
+// String returns the fingerprint as printed in the banner
+func (f *Fingerprint) String() string {
+	parts := []string{fmt.Sprintf("status %d", f.Status)}
+	if f.Size != nil {
+		parts = append(parts, fmt.Sprintf("size %d", *f.Size))
+	}
+	if f.Words != nil {
+		parts = append(parts, fmt.Sprintf("words %d", *f.Words))
+	}
+	if f.Lines != nil {
+		parts = append(parts, fmt.Sprintf("lines %d", *f.Lines))
+	}
+	if f.Redirect != "" {
+		parts = append(parts, "redirect "+f.Redirect)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// match reports whether the response has the fingerprint
+func (f *Fingerprint) match(resp *Response) bool {
+	result := resp.Result
+	if result.StatusCode != f.Status || redirectTarget(resp) != f.Redirect {
+		return false
+	}
+	if f.Size != nil && result.ContentSize != *f.Size {
+		return false
+	}
+	if f.Words != nil && int64(result.Words) != *f.Words {
+		return false
+	}
+	if f.Lines != nil && int64(result.Lines) != *f.Lines {
+		return false
+	}
+	// A baseline that only knows the status would filter every response with it
+	return f.Size != nil || f.Words != nil || f.Lines != nil || f.Redirect != ""
+}
+
+// Calibration filters the responses matching the fingerprints learned for their target
+type Calibration struct {
+	Fingerprints map[string][]*Fingerprint // Fingerprints are the baselines of each target URL.
+}
+
+// NewCalibration returns an empty calibration
+func NewCalibration() *Calibration {
+	return &Calibration{Fingerprints: make(map[string][]*Fingerprint)}
+}
+
+// Learn fingerprints the calibration responses of a target, sent for the same keyword position.
+// Responses with different status codes or redirects give a fingerprint each.
+func (c *Calibration) Learn(target string, responses []*Response) {
+	var groups [][]*Response
+	for _, resp := range responses {
+		found := false
+		for i, group := range groups {
+			if group[0].Result.StatusCode == resp.Result.StatusCode && redirectTarget(group[0]) == redirectTarget(resp) {
+				groups[i] = append(group, resp)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, []*Response{resp})
+		}
+	}
+
+	for _, group := range groups {
+		first := group[0].Result
+		f := &Fingerprint{
+			Status:   first.StatusCode,
+			Size:     stable(group, func(resp *Response) int64 { return resp.Result.ContentSize }),
+			Words:    stable(group, func(resp *Response) int64 { return int64(resp.Result.Words) }),
+			Lines:    stable(group, func(resp *Response) int64 { return int64(resp.Result.Lines) }),
+			Redirect: redirectTarget(group[0]),
+		}
+		if !c.known(target, f) {
+			c.Fingerprints[target] = append(c.Fingerprints[target], f)
+		}
+	}
+}
+
+// known reports whether the target already has the fingerprint
+func (c *Calibration) known(target string, f *Fingerprint) bool {
+	for _, known := range c.Fingerprints[target] {
+		if known.String() == f.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Calibration) Match(resp *Response) bool {
+	for _, f := range c.Fingerprints[resp.Target] {
+		if f.match(resp) {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary returns the learned fingerprints as printed in the banner, one per line, up To max lines
+func (c *Calibration) Summary(max int) []string {
+	targets := make([]string, 0, len(c.Fingerprints))
+	for target := range c.Fingerprints {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	var lines []string
+	total := 0
+	for _, target := range targets {
+		for _, f := range c.Fingerprints[target] {
+			total++
+			if len(lines) < max {
+				lines = append(lines, fmt.Sprintf("%s [%s]", target, f))
+			}
+		}
+	}
+	if total > max {
+		lines = append(lines, fmt.Sprintf("and %d more", total-max))
+	}
+	return lines
+}
+
+// stable returns the count shared by all the responses, or nil if it differs
+func stable(responses []*Response, count func(*Response) int64) *int64 {
+	n := count(responses[0])
+	for _, resp := range responses[1:] {
+		if count(resp) != n {
+			return nil
+		}
+	}
+	return &n
+}
+
+// redirectTarget returns the redirect location of the response, with the inputs replaced by their keyword,
+// so that redirects echoing the requested path give the same target
+func redirectTarget(resp *Response) string {
+	location := resp.Result.RedirectLocation
+	for keyword, word := range resp.Result.Input {
+		if word == "" {
+			continue
+		}
+		location = strings.ReplaceAll(location, word, keyword)
+		location = strings.ReplaceAll(location, neturl.PathEscape(word), keyword)
+		location = strings.ReplaceAll(location, neturl.QueryEscape(word), keyword)
+	}
+	return location
+}
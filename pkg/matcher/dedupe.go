@@ -0,0 +1,138 @@
+package matcher
+
+import (
+	"bytes"
+	"hash/fnv"
+	"math/bits"
+	neturl "net/url"
+	"sync"
+)
+
+// Dedupe clusters the responses of each host by body similarity, and only reports the first members of a cluster
+type Dedupe struct {
+	Threshold float64 // Threshold is the similarity, from 0 To 1, above which responses are in the same cluster.
+	Max       int     // Max is the number of responses reported per cluster.
+
+	mu       sync.Mutex
+	clusters map[string][]*cluster
+	next     int
+}
+
+// cluster is a group of similar responses, the first response is its reference
+type cluster struct {
+	id      int
+	hash    uint64
+	members int
+}
+
+// NewDedupe returns a dedupe for the similarity threshold, reporting max responses per cluster
+func NewDedupe(threshold float64, max int) *Dedupe {
+	return &Dedupe{Threshold: threshold, Max: max, clusters: make(map[string][]*cluster)}
+}
+
+// Add puts the response in its cluster, records the cluster in the result, and reports whether
+// the response is among the first members of the cluster
+func (d *Dedupe) Add(resp *Response) bool {
+	hash := Simhash(normalize(resp))
+
+	// Responses are only compared with the responses of the same host and status code
+	key := resp.Result.URL
+	if u, err := neturl.Parse(resp.Result.URL); err == nil {
+		key = u.Host
+	}
+	key += " " + resp.Result.Status
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, c := range d.clusters[key] {
+		if Similarity(hash, c.hash) >= d.Threshold {
+			c.members++
+			resp.Result.Cluster = c.id
+			return c.members <= d.Max
+		}
+	}
+	d.next++
+	d.clusters[key] = append(d.clusters[key], &cluster{id: d.next, hash: hash, members: 1})
+	resp.Result.Cluster = d.next
+	return true
+}
+
+// shortBody is the size under which the character shingles are added To the word pairs,
+// a short body has too few word pairs for a stable hash
+const shortBody = 256
+
+// normalize returns the body with every number replaced by 0 and the inputs replaced by their
+// keyword, so the numbers and words echoed in soft-404 pages do not tell them apart
+func normalize(resp *Response) []byte {
+	body := maskNumbers(resp.Body)
+	for keyword, word := range resp.Result.Input {
+		// Very short words would replace parts of every other word
+		if masked := maskNumbers([]byte(word)); len(masked) >= 3 {
+			body = bytes.ReplaceAll(body, masked, []byte(keyword))
+		}
+	}
+	return body
+}
+
+// maskNumbers replaces every run of digits with a single 0
+func maskNumbers(b []byte) []byte {
+	masked := make([]byte, 0, len(b))
+	for i, c := range b {
+		if c < '0' || c > '9' {
+			masked = append(masked, c)
+		} else if i == 0 || b[i-1] < '0' || b[i-1] > '9' {
+			masked = append(masked, '0')
+		}
+	}
+	return masked
+}
+
+// Simhash returns the similarity hash of the body, built from pairs of consecutive words, and
+// for short bodies from the runs of 1 To 3 characters too, so bodies that only differ by a few
+// words (timestamps, request IDs) get close hashes
+func Simhash(body []byte) uint64 {
+	var weights [64]int
+	add := func(feature []byte) {
+		h := fnv.New64a()
+		h.Write(feature)
+		sum := h.Sum64()
+		for i := range weights {
+			if sum&(1<<i) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	words := bytes.Fields(body)
+	if len(words) < 2 {
+		for _, word := range words {
+			add(word)
+		}
+	}
+	for i := 0; i+1 < len(words); i++ {
+		add(append(append(append([]byte{}, words[i]...), ' '), words[i+1]...))
+	}
+	if len(body) < shortBody {
+		for n := 1; n <= 3; n++ {
+			for i := 0; i+n <= len(body); i++ {
+				add(body[i : i+n])
+			}
+		}
+	}
+
+	var hash uint64
+	for i, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << i
+		}
+	}
+	return hash
+}
+
+// Similarity returns the similarity of two hashes, from 0 To 1
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
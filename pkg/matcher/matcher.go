@@ -0,0 +1,327 @@
+package matcher
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/common"
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+)
+
+// Modes combining the matchers of a set
+const (
+	ModeAnd = "and" // ModeAnd needs every matcher of the set To match.
+	ModeOr  = "or"  // ModeOr needs any matcher of the set To match.
+)
+
+// Scopes of the string matchers, the part of the response they look at
+const (
+	ScopeText    = "text"    // ScopeText is the text nodes of the HTML body.
+	ScopeTitle   = "title"   // ScopeTitle is the title of the HTML body.
+	ScopeBody    = "body"    // ScopeBody is the raw body.
+	ScopeHeaders = "headers" // ScopeHeaders is the "Name: value" header lines.
+	ScopeAll     = "all"     // ScopeAll is the header lines and the raw body.
+)
+
+// Scopes lists the supported scopes for -match-scope
+var Scopes = []string{ScopeText, ScopeTitle, ScopeBody, ScopeHeaders, ScopeAll}
+
+// Response is a response checked by the matchers, with the result built from it
+type Response struct {
+	Result *config.Result // Result is the result of the request.
+	Target string         // Target is the target URL, before the keywords are replaced.
+	Body   []byte         // Body is the response body.
+	Header http.Header    // Header is the response header.
+
+	Request *http.Request // Request is the sent request, only kept for -replay-proxy, it is cloned once matched.
+
+	text    string
+	title   string
+	parsed  bool
+	headers string
+}
+
+// Headers returns the response headers serialized as "Name: value" lines, sorted by name
+func (r *Response) Headers() string {
+	if r.headers == "" && len(r.Header) != 0 {
+		names := make([]string, 0, len(r.Header))
+		for name := range r.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var headers strings.Builder
+		for _, name := range names {
+			for _, value := range r.Header[name] {
+				fmt.Fprintf(&headers, "%s: %s\n", name, value)
+			}
+		}
+		r.headers = headers.String()
+	}
+	return r.headers
+}
+
+// Text returns the text nodes of the HTML body, it is parsed once
+func (r *Response) Text() string {
+	r.parse()
+	return r.text
+}
+
+// Title returns the title of the HTML body, it is parsed once
+func (r *Response) Title() string {
+	r.parse()
+	return r.title
+}
+
+// parse reads the text nodes and the title of the HTML body, the first time it is called
+func (r *Response) parse() {
+	if r.parsed {
+		return
+	}
+	r.parsed = true
+
+	doc, err := html.Parse(bytes.NewReader(r.Body))
+	if err != nil {
+		common.DebugModeEr(config.Cfg.Debug, r.Result.URL, err)
+		return
+	}
+	var text, title strings.Builder
+	var traverse func(*html.Node)
+	traverse = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+		} else if n.Type == html.ElementNode && n.Data == "title" {
+			if n.FirstChild != nil {
+				title.WriteString(n.FirstChild.Data)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			traverse(c)
+		}
+	}
+	traverse(doc)
+	r.text = text.String()
+	r.title = title.String()
+}
+
+// Scope returns the part of the response the string matchers look at
+func (r *Response) Scope(scope string) string {
+	switch scope {
+	case ScopeText:
+		return r.Text()
+	case ScopeTitle:
+		return r.Title()
+	case ScopeHeaders:
+		return r.Headers()
+	case ScopeAll:
+		return r.Headers() + "\n" + string(r.Body)
+	default:
+		return string(r.Body)
+	}
+}
+
+// Matcher checks a single criterion of a response
+type Matcher interface {
+	Match(resp *Response) bool
+}
+
+// Capturer is a matcher that records values of a matched response, such as named regex groups
+type Capturer interface {
+	Capture(resp *Response) map[string]string
+}
+
+// Set is a list of matchers combined with a mode
+type Set struct {
+	Mode     string    // Mode is how the matchers are combined (and, or).
+	Matchers []Matcher // Matchers are the criteria of the set.
+}
+
+// Match reports whether the response matches the set, an empty set never matches
+func (s *Set) Match(resp *Response) bool {
+	if len(s.Matchers) == 0 {
+		return false
+	}
+	for _, m := range s.Matchers {
+		matched := m.Match(resp)
+		if s.Mode == ModeAnd && !matched {
+			return false
+		} else if s.Mode != ModeAnd && matched {
+			return true
+		}
+	}
+	return s.Mode == ModeAnd
+}
+
+// Engine decides which responses are reported, filters are applied after the matchers
+type Engine struct {
+	Matchers Set  // Matchers are the -m* criteria, a response must match them.
+	Filters  Set  // Filters are the -f* criteria, a matched response is dropped if it matches them.
+	Default  bool // Default is set when no matcher is given, and the default status codes are matched.
+
+	Calibration *Calibration // Calibration filters the responses matching the baselines learned with -ac, nil without it.
+	Dedupe      *Dedupe      // Dedupe drops the similar responses past the first ones of a cluster, nil without -dedupe-threshold.
+}
+
+// Accept reports whether the response is reported, the captures of the matchers are
+// recorded in the result of an accepted response
+func (e *Engine) Accept(resp *Response) bool {
+	if !e.Matchers.Match(resp) || e.Filters.Match(resp) {
+		return false
+	}
+	if e.Calibration != nil && e.Calibration.Match(resp) {
+		return false
+	}
+	if e.Dedupe != nil && !e.Dedupe.Add(resp) {
+		return false
+	}
+	for _, m := range e.Matchers.Matchers {
+		capturer, ok := m.(Capturer)
+		if !ok {
+			continue
+		}
+		for name, value := range capturer.Capture(resp) {
+			if resp.Result.Captures == nil {
+				resp.Result.Captures = make(map[string]string)
+			}
+			resp.Result.Captures[name] = value
+		}
+	}
+	return true
+}
+
+// New builds the matchers and filters from the configuration, the default
+// status codes are matched when no matcher is given
+func New(cfg *config.Config) (*Engine, error) {
+	for _, mode := range []struct{ flag, value string }{{"-mmode", cfg.MatchMode}, {"-fmode", cfg.FilterMode}} {
+		if mode.value != ModeAnd && mode.value != ModeOr {
+			return nil, fmt.Errorf("Invalid value: %s, For %s (and, or)", mode.value, mode.flag)
+		}
+	}
+	if !slices.Contains(Scopes, cfg.MatchScope) {
+		return nil, fmt.Errorf("Invalid value: %s, For -match-scope (%s)", cfg.MatchScope, strings.Join(Scopes, ", "))
+	}
+	engine := &Engine{
+		Matchers: Set{Mode: cfg.MatchMode},
+		Filters:  Set{Mode: cfg.FilterMode},
+	}
+
+	var err error
+	engine.Matchers.Matchers, err = build(criteria{
+		prefix:  "m",
+		scope:   cfg.MatchScope,
+		status:  cfg.MatchStatus,
+		sizes:   cfg.MatchContentSize,
+		words:   cfg.MatchWords,
+		lines:   cfg.MatchLines,
+		times:   cfg.MatchTime,
+		strings: cfg.MatchStrings,
+		regexes: cfg.MatchRegex,
+		headers: cfg.MatchHeaders,
+		expr:    cfg.MatchExpr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	engine.Filters.Matchers, err = build(criteria{
+		prefix:  "f",
+		scope:   cfg.MatchScope,
+		status:  cfg.FilterStatus,
+		sizes:   cfg.FilterContentSize,
+		words:   cfg.FilterWords,
+		lines:   cfg.FilterLines,
+		times:   cfg.FilterTime,
+		strings: cfg.FilterStrings,
+		regexes: cfg.FilterRegex,
+		headers: cfg.FilterHeaders,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(engine.Matchers.Matchers) == 0 {
+		engine.Matchers.Matchers = []Matcher{DefaultStatus()}
+		engine.Default = true
+	}
+
+	if cfg.DedupeThreshold < 0 || cfg.DedupeThreshold > 1 {
+		return nil, fmt.Errorf("Invalid value: %v, For -dedupe-threshold (0 To 1)", cfg.DedupeThreshold)
+	}
+	if cfg.DedupeMax < 1 {
+		return nil, fmt.Errorf("Invalid value: %d, For -dedupe-max (1 or more)", cfg.DedupeMax)
+	}
+	if cfg.DedupeThreshold > 0 {
+		engine.Dedupe = NewDedupe(cfg.DedupeThreshold, cfg.DedupeMax)
+	}
+	return engine, nil
+}
+
+// criteria are the flag values of the matchers or the filters
+type criteria struct {
+	prefix  string // prefix is the first letter of the flags, m for the matchers and f for the filters.
+	scope   string // scope is the part of the response the strings are looked for in.
+	status  []string
+	sizes   []string
+	words   []string
+	lines   []string
+	times   []string
+	strings []string
+	regexes []string
+	headers []string
+	expr    string
+}
+
+// build returns a matcher for each criterion that is given
+func build(c criteria) ([]Matcher, error) {
+	var matchers []Matcher
+	if len(c.status) != 0 {
+		m, err := NewStatus(c.status)
+		if err != nil {
+			return nil, fmt.Errorf("%v, For -%sc (e.g., 200, 200-299, >=500, all)", err, c.prefix)
+		}
+		matchers = append(matchers, m)
+	}
+	for _, number := range []struct {
+		flag   string
+		values []string
+		new    func([]string) (*NumberMatcher, error)
+	}{{"l", c.sizes, NewSize}, {"w", c.words, NewWords}, {"lc", c.lines, NewLines}, {"t", c.times, NewTime}} {
+		if len(number.values) == 0 {
+			continue
+		}
+		m, err := number.new(number.values)
+		if err != nil {
+			return nil, fmt.Errorf("%v, For -%s%s (e.g., 100, 0-100, >5000, <100)", err, c.prefix, number.flag)
+		}
+		matchers = append(matchers, m)
+	}
+	if len(c.strings) != 0 {
+		matchers = append(matchers, NewString(c.strings, c.scope))
+	}
+	if len(c.regexes) != 0 {
+		m, err := NewRegex(c.regexes)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	if len(c.headers) != 0 {
+		m, err := NewHeader(c.headers)
+		if err != nil {
+			return nil, fmt.Errorf("%v, For -%sh", err, c.prefix)
+		}
+		matchers = append(matchers, m)
+	}
+	if c.expr != "" {
+		m, err := NewExpr(c.expr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value: %s, For -match-expr (%v)", c.expr, err)
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
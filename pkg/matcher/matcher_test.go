@@ -0,0 +1,222 @@
+package matcher
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+)
+
+// response returns a response with the given status, body and headers, as built by the requests
+func response(status int, body string, header http.Header) *Response {
+	return &Response{
+		Result: &config.Result{
+			URL:         "http://example.com/admin",
+			StatusCode:  status,
+			Status:      http.StatusText(status),
+			ContentSize: int64(len(body)),
+			Words:       len(body) / 5,
+			Lines:       1,
+			Ttaken:      120 * time.Millisecond,
+		},
+		Target: "http://example.com/FUZZ",
+		Body:   []byte(body),
+		Header: header,
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	must := func(m Matcher, err error) Matcher {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	page := `<html><head><title>Admin Panel</title></head><body>Welcome <b>root</b></body></html>`
+	header := http.Header{"Server": {"nginx/1.25"}, "X-Cache": {"HIT"}}
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		resp    *Response
+		want    bool
+	}{
+		{"status code", must(NewStatus([]string{"200"})), response(200, "", nil), true},
+		{"status range", must(NewStatus([]string{"300-399"})), response(200, "", nil), false},
+		{"status comparison", must(NewStatus([]string{">=500"})), response(503, "", nil), true},
+		{"status all", must(NewStatus([]string{"all"})), response(418, "", nil), true},
+		{"default status", DefaultStatus(), response(404, "", nil), false},
+		{"size", must(NewSize([]string{"<100"})), response(200, page, nil), true},
+		{"words", must(NewWords([]string{"0-5"})), response(200, page, nil), false},
+		{"lines", must(NewLines([]string{"1"})), response(200, page, nil), true},
+		{"time", must(NewTime([]string{">100"})), response(200, page, nil), true},
+		{"string ignores case", NewString([]string{"ADMIN"}, ScopeBody), response(200, page, nil), true},
+		{"string in text", NewString([]string{"welcome root"}, ScopeText), response(200, page, nil), true},
+		{"string not in title", NewString([]string{"welcome"}, ScopeTitle), response(200, page, nil), false},
+		{"string in headers", NewString([]string{"nginx"}, ScopeHeaders), response(200, page, header), true},
+		{"string not in body", NewString([]string{"nginx"}, ScopeBody), response(200, page, header), false},
+		{"regex on body", must(NewRegex([]string{`<title>(?P<title>[^<]+)`})), response(200, page, nil), true},
+		{"regex on headers", must(NewRegex([]string{`(?m)^Server: nginx`})), response(200, "", header), true},
+		{"regex no match", must(NewRegex([]string{`^\d+$`})), response(200, page, nil), false},
+		{"header exists", must(NewHeader([]string{"X-Cache"})), response(200, "", header), true},
+		{"header missing", must(NewHeader([]string{"X-Powered-By"})), response(200, "", header), false},
+		{"header value", must(NewHeader([]string{"x-cache: hit"})), response(200, "", header), true},
+		{"header regex", must(NewHeader([]string{`Server: /^nginx\/1\.2\d$/`})), response(200, "", header), true},
+		{"expression", must(NewExpr(`status == 200 && contains(body, "root")`)), response(200, page, nil), true},
+		{"expression header", must(NewExpr(`header("X-Cache") == "MISS"`)), response(200, "", header), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher.Match(tt.resp); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// constant is a matcher with a fixed answer, it counts how often it is asked
+type constant struct {
+	match bool
+	calls int
+}
+
+func (c *constant) Match(*Response) bool {
+	c.calls++
+	return c.match
+}
+
+func TestSetMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		matches []bool
+		want    bool
+		calls   int // calls is the number of matchers asked, the set stops at the first deciding one
+	}{
+		{"empty and", ModeAnd, nil, false, 0},
+		{"empty or", ModeOr, nil, false, 0},
+		{"and all", ModeAnd, []bool{true, true}, true, 2},
+		{"and one fails", ModeAnd, []bool{true, false, true}, false, 2},
+		{"or none", ModeOr, []bool{false, false}, false, 2},
+		{"or one", ModeOr, []bool{false, true, false}, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := Set{Mode: tt.mode}
+			var matchers []*constant
+			for _, match := range tt.matches {
+				m := &constant{match: match}
+				matchers = append(matchers, m)
+				set.Matchers = append(set.Matchers, m)
+			}
+			if got := set.Match(response(200, "", nil)); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+			calls := 0
+			for _, m := range matchers {
+				calls += m.calls
+			}
+			if calls != tt.calls {
+				t.Errorf("Match() asked %d matchers, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestEngineAccept(t *testing.T) {
+	calibrated := NewCalibration()
+	calibrated.Learn("http://example.com/FUZZ", []*Response{response(404, "not found", nil), response(404, "not found", nil)})
+
+	tests := []struct {
+		name     string
+		matchers []Matcher
+		filters  []Matcher
+		calib    bool
+		dedupe   bool
+		resps    []*Response
+		want     []bool
+		captures bool // captures is whether the last accepted response has the regex captures
+	}{
+		{
+			name:     "matched",
+			matchers: []Matcher{&constant{match: true}},
+			resps:    []*Response{response(200, "ok", nil)},
+			want:     []bool{true},
+		},
+		{
+			name:     "not matched",
+			matchers: []Matcher{&constant{match: false}},
+			resps:    []*Response{response(200, "ok", nil)},
+			want:     []bool{false},
+		},
+		{
+			name:     "filtered after matching",
+			matchers: []Matcher{&constant{match: true}},
+			filters:  []Matcher{&constant{match: true}},
+			resps:    []*Response{response(200, "ok", nil)},
+			want:     []bool{false},
+		},
+		{
+			name:     "calibrated responses",
+			matchers: []Matcher{&constant{match: true}},
+			calib:    true,
+			resps:    []*Response{response(404, "not found", nil), response(200, "not found", nil)},
+			want:     []bool{false, true},
+		},
+		{
+			name:     "filtered responses are not deduplicated",
+			matchers: []Matcher{DefaultStatus()},
+			filters:  []Matcher{NewString([]string{"draft"}, ScopeBody)},
+			dedupe:   true,
+			resps:    []*Response{response(200, "page one draft", nil), response(200, "page one", nil), response(200, "page one", nil)},
+			want:     []bool{false, true, false},
+		},
+		{
+			name:     "captures of accepted responses",
+			matchers: []Matcher{mustRegex(t, `id=(?P<id>\d+)`)},
+			resps:    []*Response{response(200, "id=42", nil)},
+			want:     []bool{true},
+			captures: true,
+		},
+		{
+			name:     "no captures once filtered",
+			matchers: []Matcher{mustRegex(t, `id=(?P<id>\d+)`)},
+			filters:  []Matcher{&constant{match: true}},
+			resps:    []*Response{response(200, "id=42", nil)},
+			want:     []bool{false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := &Engine{
+				Matchers: Set{Mode: ModeOr, Matchers: tt.matchers},
+				Filters:  Set{Mode: ModeOr, Matchers: tt.filters},
+			}
+			if tt.calib {
+				engine.Calibration = calibrated
+			}
+			if tt.dedupe {
+				engine.Dedupe = NewDedupe(0.9, 1)
+			}
+			var last *Response
+			for i, resp := range tt.resps {
+				if got := engine.Accept(resp); got != tt.want[i] {
+					t.Errorf("Accept(response %d) = %v, want %v", i, got, tt.want[i])
+				}
+				last = resp
+			}
+			if got := last.Result.Captures["id"] == "42"; got != tt.captures {
+				t.Errorf("captures = %v, want id 42: %v", last.Result.Captures, tt.captures)
+			}
+		})
+	}
+}
+
+func mustRegex(t *testing.T, value string) *RegexMatcher {
+	m, err := NewRegex([]string{value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
@@ -0,0 +1,285 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/expr"
+)
+
+// DefaultStatusCodes are the status codes matched when no matcher is given
+var DefaultStatusCodes = []string{"200-299", "301", "302", "307", "401", "403", "405", "500"}
+
+// StatusMatcher matches the status code of the response
+type StatusMatcher struct {
+	All    bool   // All matches every status code.
+	Ranges Ranges // Ranges are the matched status codes.
+}
+
+// NewStatus returns a status matcher for the given codes, ranges and comparisons, "all" matches every code
+func NewStatus(values []string) (*StatusMatcher, error) {
+	m := &StatusMatcher{}
+	for _, value := range values {
+		if value == "all" {
+			m.All = true
+			continue
+		}
+		rng, err := ParseRange(value)
+		if err != nil {
+			return nil, err
+		}
+		m.Ranges = append(m.Ranges, rng)
+	}
+	return m, nil
+}
+
+// DefaultStatus returns the status matcher used when no matcher is given
+func DefaultStatus() *StatusMatcher {
+	m, _ := NewStatus(DefaultStatusCodes)
+	return m
+}
+
+func (m *StatusMatcher) Match(resp *Response) bool {
+	return m.All || m.Ranges.Contains(int64(resp.Result.StatusCode))
+}
+
+// NumberMatcher matches a number of the response, such as its size, words, lines or time
+type NumberMatcher struct {
+	Ranges Ranges                     // Ranges are the matched numbers.
+	Number func(*config.Result) int64 // Number returns the number of the result.
+}
+
+// newNumber returns a number matcher for the given numbers, ranges and comparisons
+func newNumber(values []string, number func(*config.Result) int64) (*NumberMatcher, error) {
+	ranges, err := ParseRanges(values)
+	if err != nil {
+		return nil, err
+	}
+	return &NumberMatcher{Ranges: ranges, Number: number}, nil
+}
+
+// NewSize returns a matcher for the content size in bytes
+func NewSize(values []string) (*NumberMatcher, error) {
+	return newNumber(values, func(result *config.Result) int64 { return result.ContentSize })
+}
+
+// NewWords returns a matcher for the number of words in the body
+func NewWords(values []string) (*NumberMatcher, error) {
+	return newNumber(values, func(result *config.Result) int64 { return int64(result.Words) })
+}
+
+// NewLines returns a matcher for the number of lines in the body
+func NewLines(values []string) (*NumberMatcher, error) {
+	return newNumber(values, func(result *config.Result) int64 { return int64(result.Lines) })
+}
+
+// NewTime returns a matcher for the response time in milliseconds
+func NewTime(values []string) (*NumberMatcher, error) {
+	return newNumber(values, func(result *config.Result) int64 { return result.Ttaken.Milliseconds() })
+}
+
+func (m *NumberMatcher) Match(resp *Response) bool {
+	return m.Ranges.Contains(m.Number(resp.Result))
+}
+
+// StringMatcher matches a scope of the response against strings, ignoring case
+type StringMatcher struct {
+	Strings []string // Strings are the matched strings, in lower case.
+	Scope   string   // Scope is the part of the response the strings are looked for in, the raw body by default.
+}
+
+// NewString returns a string matcher, any of the strings found in the scope matches
+func NewString(values []string, scope string) *StringMatcher {
+	m := &StringMatcher{Scope: scope}
+	for _, value := range values {
+		m.Strings = append(m.Strings, strings.ToLower(value))
+	}
+	return m
+}
+
+func (m *StringMatcher) Match(resp *Response) bool {
+	text := strings.ToLower(resp.Scope(m.Scope))
+	for _, s := range m.Strings {
+		if strings.Contains(text, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegexMatcher matches the raw response body and the serialized headers against regular expressions
+type RegexMatcher struct {
+	Regexes []*regexp.Regexp // Regexes are the matched expressions, any of them matches.
+}
+
+// NewRegex returns a regex matcher, the expressions use the Go syntax
+func NewRegex(values []string) (*RegexMatcher, error) {
+	m := &RegexMatcher{}
+	for _, value := range values {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %s: %v", value, err)
+		}
+		m.Regexes = append(m.Regexes, re)
+	}
+	return m, nil
+}
+
+func (m *RegexMatcher) Match(resp *Response) bool {
+	for _, re := range m.Regexes {
+		if re.Match(resp.Body) || re.MatchString(resp.Headers()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capture returns the named groups of the first match of each expression, in the body then the headers
+func (m *RegexMatcher) Capture(resp *Response) map[string]string {
+	captures := make(map[string]string)
+	for _, re := range m.Regexes {
+		match := re.FindSubmatch(resp.Body)
+		if match == nil {
+			if header := re.FindStringSubmatch(resp.Headers()); header != nil {
+				match = make([][]byte, len(header))
+				for i, group := range header {
+					match[i] = []byte(group)
+				}
+			}
+		}
+		for i, name := range re.SubexpNames() {
+			if name == "" || i >= len(match) || len(match[i]) == 0 {
+				continue
+			}
+			if _, ok := captures[name]; !ok {
+				captures[name] = string(match[i])
+			}
+		}
+	}
+	return captures
+}
+
+// HeaderMatcher matches response headers by name, and optionally by value
+type HeaderMatcher struct {
+	Headers []HeaderMatch // Headers are the matched headers, any of them matches.
+}
+
+// HeaderMatch is a single header criterion, "Name" for existence, "Name: value" for a
+// substring of the value ignoring case, and "Name: /regex/" for a regex on the value
+type HeaderMatch struct {
+	Name  string         // Name is the header name.
+	Value string         // Value is the substring looked for in the header value, in lower case.
+	Regex *regexp.Regexp // Regex is matched against the header value, if given.
+}
+
+// NewHeader returns a header matcher
+func NewHeader(values []string) (*HeaderMatcher, error) {
+	m := &HeaderMatcher{}
+	for _, value := range values {
+		name, val, _ := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		val = strings.TrimSpace(val)
+		if name == "" {
+			return nil, fmt.Errorf("invalid header: %s", value)
+		}
+
+		header := HeaderMatch{Name: name}
+		if len(val) > 1 && strings.HasPrefix(val, "/") && strings.HasSuffix(val, "/") {
+			re, err := regexp.Compile(val[1 : len(val)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid header regex: %s: %v", value, err)
+			}
+			header.Regex = re
+		} else {
+			header.Value = strings.ToLower(val)
+		}
+		m.Headers = append(m.Headers, header)
+	}
+	return m, nil
+}
+
+func (m *HeaderMatcher) Match(resp *Response) bool {
+	for _, header := range m.Headers {
+		for _, value := range resp.Header.Values(header.Name) {
+			if header.Regex != nil {
+				if header.Regex.MatchString(value) {
+					return true
+				}
+			} else if strings.Contains(strings.ToLower(value), header.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExprMatcher matches the responses for which an expression is true
+type ExprMatcher struct {
+	Expr *expr.Expr // Expr is the compiled expression.
+}
+
+// NewExpr returns an expression matcher, the expression is compiled and type checked
+func NewExpr(value string) (*ExprMatcher, error) {
+	e, err := expr.Compile(value)
+	if err != nil {
+		return nil, err
+	}
+	return &ExprMatcher{Expr: e}, nil
+}
+
+func (m *ExprMatcher) Match(resp *Response) bool {
+	return m.Expr.Eval(exprEnv{resp})
+}
+
+// exprEnv gives the values of the expression variables for a response
+type exprEnv struct {
+	resp *Response
+}
+
+func (env exprEnv) Int(name string) int64 {
+	result := env.resp.Result
+	switch name {
+	case "status":
+		return int64(result.StatusCode)
+	case "size":
+		return result.ContentSize
+	case "words":
+		return int64(result.Words)
+	case "lines":
+		return int64(result.Lines)
+	case "duration":
+		return result.Ttaken.Milliseconds()
+	}
+	return 0
+}
+
+func (env exprEnv) String(name string) string {
+	result := env.resp.Result
+	switch name {
+	case "body":
+		return string(env.resp.Body)
+	case "headers":
+		return env.resp.Headers()
+	case "title":
+		return env.resp.Title()
+	case "url":
+		return result.URL
+	case "method":
+		return result.Method
+	case "content_type":
+		return result.ContentType
+	case "redirect":
+		return result.RedirectLocation
+	}
+	return ""
+}
+
+func (env exprEnv) Header(name string) (string, bool) {
+	values := env.resp.Header.Values(name)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
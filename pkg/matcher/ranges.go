@@ -0,0 +1,95 @@
+package matcher
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Range is an inclusive range of numbers, a single number has Min equal To Max
+type Range struct {
+	Min int64 // Min is the lowest number of the range.
+	Max int64 // Max is the highest number of the range.
+}
+
+// Contains reports whether n is in the range
+func (r Range) Contains(n int64) bool {
+	return n >= r.Min && n <= r.Max
+}
+
+// Ranges is a list of ranges, a number matches if it is in any of them
+type Ranges []Range
+
+// Contains reports whether n is in any of the ranges
+func (r Ranges) Contains(n int64) bool {
+	for _, rng := range r {
+		if rng.Contains(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRanges parses numbers, ranges and comparisons, such as 200, 200-299, >5000, <100, >=400 or <=10
+func ParseRanges(values []string) (Ranges, error) {
+	var ranges Ranges
+	for _, value := range values {
+		rng, err := ParseRange(value)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, rng)
+	}
+	return ranges, nil
+}
+
+// ParseRange parses a single number, range or comparison
+func ParseRange(value string) (Range, error) {
+	value = strings.TrimSpace(value)
+	invalid := fmt.Errorf("Invalid value: %s", value)
+
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(value, op) {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value[len(op):]), 10, 64)
+		if err != nil {
+			return Range{}, invalid
+		}
+		switch op {
+		case ">=":
+			return Range{Min: n, Max: math.MaxInt64}, nil
+		case "<=":
+			return Range{Min: math.MinInt64, Max: n}, nil
+		case ">":
+			if n == math.MaxInt64 {
+				return Range{}, invalid
+			}
+			return Range{Min: n + 1, Max: math.MaxInt64}, nil
+		default:
+			if n == math.MinInt64 {
+				return Range{}, invalid
+			}
+			return Range{Min: math.MinInt64, Max: n - 1}, nil
+		}
+	}
+
+	if low, high, ok := strings.Cut(value, "-"); ok && low != "" {
+		min, err := strconv.ParseInt(strings.TrimSpace(low), 10, 64)
+		if err != nil {
+			return Range{}, invalid
+		}
+		max, err := strconv.ParseInt(strings.TrimSpace(high), 10, 64)
+		if err != nil || max < min {
+			return Range{}, invalid
+		}
+		return Range{Min: min, Max: max}, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return Range{}, invalid
+	}
+	return Range{Min: n, Max: n}, nil
+}
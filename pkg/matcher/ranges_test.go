@@ -0,0 +1,72 @@
+package matcher
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Range
+		wantErr bool
+	}{
+		{value: "200", want: Range{Min: 200, Max: 200}},
+		{value: " 404 ", want: Range{Min: 404, Max: 404}},
+		{value: "200-299", want: Range{Min: 200, Max: 299}},
+		{value: "0 - 100", want: Range{Min: 0, Max: 100}},
+		{value: ">5000", want: Range{Min: 5001, Max: math.MaxInt64}},
+		{value: ">=400", want: Range{Min: 400, Max: math.MaxInt64}},
+		{value: "<100", want: Range{Min: math.MinInt64, Max: 99}},
+		{value: "<= 10", want: Range{Min: math.MinInt64, Max: 10}},
+		{value: "-5", want: Range{Min: -5, Max: -5}},
+		{value: "", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "299-200", wantErr: true},
+		{value: "200-", wantErr: true},
+		{value: ">", wantErr: true},
+		{value: "=>100", wantErr: true},
+		{value: ">9223372036854775807", wantErr: true},
+		{value: "<-9223372036854775808", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRange(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRange(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRange(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRangesContains(t *testing.T) {
+	ranges, err := ParseRanges([]string{"200-299", "301", ">=500"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		n    int64
+		want bool
+	}{
+		{199, false},
+		{200, true},
+		{299, true},
+		{300, false},
+		{301, true},
+		{499, false},
+		{500, true},
+		{math.MaxInt64, true},
+	}
+	for _, tt := range tests {
+		if got := ranges.Contains(tt.n); got != tt.want {
+			t.Errorf("Contains(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+
+	if _, err := ParseRanges([]string{"200", "bad"}); err == nil {
+		t.Errorf("ParseRanges with an invalid value returned no error")
+	}
+}
@@ -2,14 +2,20 @@ package opt
 
 import (
 	"fmt"
+	"net/http"
 	neturl "net/url"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/projectdiscovery/gologger"
 	"github.com/schollz/progressbar/v3"
 
 	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/matcher"
+	"github.com/SpeedyQweku/qfuzz/pkg/output"
+	"github.com/SpeedyQweku/qfuzz/pkg/payload"
 )
 
 // var (
@@ -19,156 +25,283 @@ import (
 // )
 
 // processUrls process the urls
-func ProcessUrls(url, word string, cfg config.Config) string {
-	var fullURL string
+func ProcessUrls(url string, inputs map[string]string, cfg *config.Config) string {
+	if word, ok := inputs[url]; ok {
+		return word
+	}
 
-	if url == "FUZZ" {
-		fullURL = strings.ReplaceAll(url, "FUZZ", word)
-		return fullURL
-	} else {
-		urls, err := neturl.Parse(url)
-		if err != nil {
-			gologger.Error().Msgf(config.Red + "Invalid URL: " + url + config.Reset)
-			// return
-		}
-		if urls.Scheme == "" {
-			urls.Scheme = "https"
-		}
+	if _, err := neturl.Parse(url); err != nil {
+		gologger.Error().Msgf(config.Red + "Invalid URL: " + url + config.Reset)
+	}
+	if !strings.Contains(url, "://") {
+		url = "https://" + url
+	}
 
-		if strings.HasPrefix(word, "/") {
-			word = strings.TrimLeft(word, "/")
-		}
+	words := make(map[string]string, len(inputs))
+	for keyword, word := range inputs {
+		words[keyword] = strings.TrimLeft(word, "/")
+	}
 
-		if strings.Contains(config.Cfg.PostData, "FUZZ") || CheckFUZZheader(config.Cfg.Headers) {
-			// fmt.Println(config.Cfg.PostData)
-			fullURL = urls.String()
-			// } else if headerFUZZcheck(config.Cfg.Headers) {
-			// 	// fmt.Println(header)
-			// 	fullURL = urls.String()
-		} else if strings.Contains(urls.String(), "FUZZ") {
-			fullURL = strings.Replace(urls.String(), "FUZZ", word, 1)
-		} else if strings.HasSuffix(urls.String(), "/") {
-			urlstr := strings.TrimRight(urls.String(), "/")
-			fullURL = fmt.Sprintf("%s/%s", urlstr, word)
-		} else {
-			fullURL = fmt.Sprintf("%s/%s", urls.String(), word)
-		}
+	fullURL := payload.Replace(url, words)
+	if payload.Contains(url, words) || payload.Contains(cfg.PostData, words) || payload.ContainsAny(cfg.Headers, words) || payload.Contains(cfg.HttpMethod, words) || len(words) != 1 {
 		return fullURL
 	}
-}
 
-// Save the URL To the success file
-func SaveSfile(url string) {
-	if len(config.Cfg.OutputFile) != 0 {
-		if config.Cfg.SuccessFile != nil {
-			_, err := fmt.Fprintf(config.Cfg.SuccessFile, "%s\n", url)
-			if err != nil {
-				gologger.Fatal().Msgf("Error writing To success file: %v\n", err)
-			}
-		}
-	} else {
-		return
+	// A single wordlist with no keyword placed anywhere is appended To the path
+	var word string
+	for _, w := range words {
+		word = w
 	}
+	return fmt.Sprintf("%s/%s", strings.TrimRight(fullURL, "/"), word)
 }
 
-// validateConfig performs initial validation on the configuration
-func ValidateConfig() {
-	// Validate status codes and sizes
-	var statusList []interface{}
-	for _, status := range config.Cfg.MatchStatus {
-		if status == "all" {
-			break
-		} else {
-			statusList = append(statusList, status)
-		}
-	}
-	for _, status := range config.Cfg.FilterStatus {
-		if status == "all" {
-			break
-		} else {
-			statusList = append(statusList, status)
-		}
+// outputWriter writes the results To the success file in the -of format
+var outputWriter output.Writer
+
+// OpenOutputFile creates the success file and its writer
+func OpenOutputFile() {
+	if config.Cfg.OutputFile == "" {
+		return
 	}
-	if !CheckNumber(statusList) {
-		gologger.Fatal().Msgf("Invalid value: %v, For -fc/-mc", statusList)
+	file, err := os.Create(config.Cfg.OutputFile)
+	if err != nil {
+		gologger.Fatal().Msgf("Error creating success file: %v", err)
 	}
+	config.Cfg.SuccessFile = file
 
-	var sizeList []interface{}
-	for _, size := range config.Cfg.MatchContentSize {
-		sizeList = append(sizeList, size)
+	outputWriter, err = output.New(config.Cfg.OutputFormat, file, ScanInfo())
+	if err != nil {
+		gologger.Fatal().Msgf("Error creating success file: %v", err)
 	}
-	for _, size := range config.Cfg.FilterContentSize {
-		sizeList = append(sizeList, size)
+}
+
+// Save the result To the success file
+func SaveResult(result *config.Result) {
+	if outputWriter == nil {
+		return
 	}
-	if !CheckNumber(sizeList) {
-		gologger.Fatal().Msgf("Invalid value: %v, For -fl/-ml", sizeList)
+	if err := outputWriter.Write(result); err != nil {
+		gologger.Fatal().Msgf("Error writing To success file: %v\n", err)
 	}
+}
 
+// validateConfig performs initial validation on the configuration
+func ValidateConfig() {
 	// Check necessary configurations
+	noTarget := config.Cfg.UrlFile == "" && len(config.Cfg.UrlString) == 0 && config.Cfg.RequestFile == ""
 	if !config.Cfg.WebCache {
-		if config.Cfg.WordlistFile == "" && (config.Cfg.UrlFile == "" && len(config.Cfg.UrlString) == 0) {
-			gologger.Fatal().Msgf(config.Red + "Please specify wordlist and target using -w/-wordlist, -l or -u" + config.Reset)
-		} else if config.Cfg.WordlistFile == "" {
+		if len(config.Cfg.Wordlists) == 0 && noTarget {
+			gologger.Fatal().Msgf(config.Red + "Please specify wordlist and target using -w/-wordlist, -l, -u or -request" + config.Reset)
+		} else if len(config.Cfg.Wordlists) == 0 {
 			gologger.Fatal().Msgf(config.Red + "Please specify target using -w/-wordlist" + config.Reset)
-		} else if config.Cfg.UrlFile == "" && len(config.Cfg.UrlString) == 0 {
-			gologger.Fatal().Msgf(config.Red + "Please specify target using -l or -u" + config.Reset)
+		} else if noTarget {
+			gologger.Fatal().Msgf(config.Red + "Please specify target using -l, -u or -request" + config.Reset)
 		}
 	} else {
-		if config.Cfg.UrlFile == "" && len(config.Cfg.UrlString) == 0 {
-			gologger.Fatal().Msgf(config.Red + "Please specify target using -l or -u" + config.Reset)
+		if noTarget {
+			gologger.Fatal().Msgf(config.Red + "Please specify target using -l, -u or -request" + config.Reset)
 		}
 	}
 
-	if !config.Cfg.WebCache {
-		if !strings.HasSuffix(config.Cfg.WordlistFile, ".txt") || (!strings.HasSuffix(config.Cfg.UrlFile, ".txt") && len(config.Cfg.UrlString) == 0) {
+	keywords := make(map[string]bool)
+	for _, item := range config.Cfg.Wordlists {
+		wordlist := ParseWordlist(item)
+		if !strings.HasSuffix(wordlist.Path, ".txt") {
 			gologger.Fatal().Msgf(config.Red + "Wordlist and target files must have .txt extension." + config.Reset)
 		}
-	} else {
-		if !strings.HasSuffix(config.Cfg.UrlFile, ".txt") && len(config.Cfg.UrlString) == 0 {
-			gologger.Fatal().Msgf(config.Red + "Target file must have .txt extension." + config.Reset)
+		if keywords[wordlist.Keyword] {
+			gologger.Fatal().Msgf("%sKeyword %s is bound To more than one wordlist%s", config.Red, wordlist.Keyword, config.Reset)
 		}
+		keywords[wordlist.Keyword] = true
+	}
+	if config.Cfg.UrlFile != "" && !strings.HasSuffix(config.Cfg.UrlFile, ".txt") && len(config.Cfg.UrlString) == 0 {
+		gologger.Fatal().Msgf(config.Red + "Target file must have .txt extension." + config.Reset)
+	}
+	if !slices.Contains(output.Formats, config.Cfg.OutputFormat) {
+		gologger.Fatal().Msgf("%sInvalid value: %s, For -of (%s)%s", config.Red, config.Cfg.OutputFormat, strings.Join(output.Formats, ", "), config.Reset)
+	}
+	if config.Cfg.RequestProto != "http" && config.Cfg.RequestProto != "https" {
+		gologger.Fatal().Msgf("%sInvalid value: %s, For -request-proto (http, https)%s", config.Red, config.Cfg.RequestProto, config.Reset)
+	}
+	switch config.Cfg.Mode {
+	case config.ModeClusterbomb, config.ModePitchfork, config.ModeSniper:
+	default:
+		gologger.Fatal().Msgf("%sInvalid value: %s, For -mode (clusterbomb, pitchfork, sniper)%s", config.Red, config.Cfg.Mode, config.Reset)
+	}
+	if len(config.Cfg.CustomCalibration) != 0 {
+		config.Cfg.AutoCalibration = true
 	}
 	if config.Cfg.Concurrency == 0 {
 		gologger.Fatal().Msgf("%s-c Can't Be 0%s", config.Red, config.Reset)
 	}
-	if (len(config.Cfg.MatchStatus) != 0 || len(config.Cfg.MatchStrings) != 0 || len(config.Cfg.MatchContentSize) != 0) && (len(config.Cfg.FilterStatus) != 0 || len(config.Cfg.FilterStrings) != 0 || len(config.Cfg.FilterContentSize) != 0) {
-		gologger.Fatal().Msgf("%sCan't run any of the Match(s) and Filter(s) at the same time now%s", config.Red, config.Reset)
+	for _, limit := range []struct {
+		flag  string
+		value int
+	}{{"-rate", config.Cfg.Rate}, {"-host-concurrency", config.Cfg.HostConcurrency}, {"-host-rate", config.Cfg.HostRate}, {"-retries", config.Cfg.Retries}, {"-retry-max-wait", config.Cfg.RetryMaxWait}} {
+		if limit.value < 0 {
+			gologger.Fatal().Msgf("%sInvalid value: %d, For %s (0 or more)%s", config.Red, limit.value, limit.flag, config.Reset)
+		}
+	}
+	var err error
+	if RetryCodes, err = matcher.ParseRanges(config.Cfg.RetryCodes); err != nil {
+		gologger.Fatal().Msgf("%s%v, For -retry-codes (e.g., 429, 503, 520-529)%s", config.Red, err, config.Reset)
+	}
+	Proxies = ReadProxies()
+	if config.Cfg.ReplayProxy != "" {
+		if ReplayProxy, err = ParseProxy(config.Cfg.ReplayProxy); err != nil {
+			gologger.Fatal().Msgf("%sInvalid value: %s, For -replay-proxy (%s)%s", config.Red, config.Cfg.ReplayProxy, strings.Join(ProxySchemes, ", "), config.Reset)
+		}
+	}
+
+	// Build the matchers and filters
+	if Matchers, err = matcher.New(&config.Cfg); err != nil {
+		gologger.Fatal().Msgf("%s%v%s", config.Red, err, config.Reset)
+	}
+}
+
+// RetryCodes are the status codes the requests are retried on, built by ValidateConfig
+var RetryCodes matcher.Ranges
+
+// SetHostConcurrency keeps the connections of the client To each host within -host-concurrency,
+// the transport allows 500 otherwise
+func SetHostConcurrency(client *http.Client, n int) {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok || n <= 0 {
+		return
+	}
+	transport.MaxConnsPerHost = n
+	transport.MaxIdleConnsPerHost = n
+}
+
+// SetIdleConns keeps at most n idle connections in the client, so that the open sockets
+// stay within -c and a scan of many hosts does not run out of file descriptors
+func SetIdleConns(client *http.Client, n int) {
+	if transport, ok := client.Transport.(*http.Transport); ok && n > 0 {
+		transport.MaxIdleConns = n
+	}
+}
+
+// ScanInfo returns the scan configuration, as printed in the banner and the HTML report
+func ScanInfo() []output.Field {
+	var info []output.Field
+	add := func(name string, value interface{}) {
+		info = append(info, output.Field{Name: name, Value: fmt.Sprint(value)})
+	}
+
+	if config.Cfg.HttpMethod == "" {
+		add("HTTP Method", "[GET]")
+	} else {
+		add("HTTP Method", "["+strings.ToUpper(config.Cfg.HttpMethod)+"]")
+	}
+	add("Follow redirects", config.Cfg.FollowRedirect)
+	if len(Proxies) != 0 {
+		add("Proxy", ProxySummary(Proxies))
+	}
+	if ReplayProxy != nil {
+		add("Replay Proxy", ReplayProxy.Redacted())
+	}
+	if config.Cfg.Rate > 0 {
+		add("Rate", fmt.Sprintf("%d requests/sec", config.Cfg.Rate))
 	}
+	if config.Cfg.Retries > 0 && len(RetryCodes) != 0 {
+		add("Retries", fmt.Sprintf("%d on %s, waiting up To %ds", config.Cfg.Retries, strings.Join(config.Cfg.RetryCodes, ","), config.Cfg.RetryMaxWait))
+	}
+	if config.Cfg.HostConcurrency > 0 {
+		add("Host Concurrency", config.Cfg.HostConcurrency)
+	}
+	if config.Cfg.HostRate > 0 {
+		add("Host Rate", fmt.Sprintf("%d requests/sec", config.Cfg.HostRate))
+	}
+	if len(config.Cfg.Wordlists) > 1 {
+		add("Wordlist Mode", config.Cfg.Mode)
+	}
+
+	if Matchers.Default {
+		add("Match Status Code", "["+strings.Join(matcher.DefaultStatusCodes, ",")+"]")
+	} else if len(config.Cfg.MatchStatus) != 0 {
+		add("Match Status Code", config.Cfg.MatchStatus)
+	}
+	if len(config.Cfg.MatchStrings) != 0 {
+		add("Match Strings", config.Cfg.MatchStrings)
+	}
+	if (len(config.Cfg.MatchStrings) != 0 || len(config.Cfg.FilterStrings) != 0) && config.Cfg.MatchScope != matcher.ScopeBody {
+		add("Match Scope", config.Cfg.MatchScope)
+	}
+	if len(config.Cfg.MatchRegex) != 0 {
+		add("Match Regex", config.Cfg.MatchRegex)
+	}
+	if len(config.Cfg.MatchHeaders) != 0 {
+		add("Match Headers", config.Cfg.MatchHeaders)
+	}
+	if config.Cfg.MatchExpr != "" {
+		add("Match Expression", config.Cfg.MatchExpr)
+	}
+	if len(config.Cfg.MatchContentSize) != 0 {
+		add("Match ContentSize", config.Cfg.MatchContentSize)
+	}
+	if len(config.Cfg.MatchWords) != 0 {
+		add("Match Words", config.Cfg.MatchWords)
+	}
+	if len(config.Cfg.MatchLines) != 0 {
+		add("Match Lines", config.Cfg.MatchLines)
+	}
+	if len(config.Cfg.MatchTime) != 0 {
+		add("Match Time (ms)", config.Cfg.MatchTime)
+	}
+	if len(config.Cfg.FilterStatus) != 0 {
+		add("Filter Status Code", config.Cfg.FilterStatus)
+	}
+	if len(config.Cfg.FilterStrings) != 0 {
+		add("Filter Strings", config.Cfg.FilterStrings)
+	}
+	if len(config.Cfg.FilterRegex) != 0 {
+		add("Filter Regex", config.Cfg.FilterRegex)
+	}
+	if len(config.Cfg.FilterHeaders) != 0 {
+		add("Filter Headers", config.Cfg.FilterHeaders)
+	}
+	if len(config.Cfg.FilterContentSize) != 0 {
+		add("Filter ContentSize", config.Cfg.FilterContentSize)
+	}
+	if len(config.Cfg.FilterWords) != 0 {
+		add("Filter Words", config.Cfg.FilterWords)
+	}
+	if len(config.Cfg.FilterLines) != 0 {
+		add("Filter Lines", config.Cfg.FilterLines)
+	}
+	if len(config.Cfg.FilterTime) != 0 {
+		add("Filter Time (ms)", config.Cfg.FilterTime)
+	}
+	if len(Matchers.Matchers.Matchers) > 1 {
+		add("Match Mode", Matchers.Matchers.Mode)
+	}
+	if len(Matchers.Filters.Matchers) > 1 {
+		add("Filter Mode", Matchers.Filters.Mode)
+	}
+	if Matchers.Calibration != nil {
+		summary := Matchers.Calibration.Summary(10)
+		if len(summary) == 0 {
+			add("Auto Calibration", "no baseline learned")
+		}
+		for _, line := range summary {
+			add("Auto Calibration", line)
+		}
+	}
+	if Matchers.Dedupe != nil {
+		add("Dedupe", fmt.Sprintf("similarity %v, %d per cluster", Matchers.Dedupe.Threshold, Matchers.Dedupe.Max))
+	}
+	if config.Cfg.WebCache {
+		add("Detect Web Cache", "Enabled")
+	}
+	return info
 }
 
 // progbar initializes and returns a new progress bar with the specified number of steps
 func Progbar(progNum int) *progressbar.ProgressBar {
 	if !config.Cfg.Silent {
 		gologger.Print().Msgf("%s %s", config.Banner, config.Version)
-		if config.Cfg.HttpMethod == "" {
-			gologger.Info().Msgf("HTTP Method : %s[GET]%s", config.Yellow, config.Reset)
-		} else {
-			gologger.Info().Msgf("HTTP Method : %s[%s]%s", config.Yellow, strings.ToUpper(config.Cfg.HttpMethod), config.Reset)
-		}
-		gologger.Info().Msgf("Follow redirects : %s%v%s", config.Yellow, config.Cfg.FollowRedirect, config.Reset)
-
-		if len(config.Cfg.MatchStatus) == 0 && len(config.Cfg.MatchStrings) == 0 && len(config.Cfg.MatchContentSize) == 0 || (len(config.Cfg.FilterStrings) != 0 || len(config.Cfg.FilterContentSize) != 0 || len(config.Cfg.FilterStatus) != 0) {
-			gologger.Info().Msgf("Match Status Code : %s[200-299,301,302,307,401,403,405,500]%s", config.Yellow, config.Reset)
-		} else if len(config.Cfg.MatchStatus) != 0 {
-			gologger.Info().Msgf("Match Status Code : %s%v%s", config.Yellow, config.Cfg.MatchStatus, config.Reset)
-		}
-		if len(config.Cfg.MatchStrings) != 0 {
-			gologger.Info().Msgf("Match Strings : %s%v%s", config.Yellow, config.Cfg.MatchStrings, config.Reset)
-		}
-		if len(config.Cfg.MatchContentSize) != 0 {
-			gologger.Info().Msgf("Match ContentSize : %s%v%s", config.Yellow, config.Cfg.MatchContentSize, config.Reset)
-		}
-		if len(config.Cfg.FilterStatus) != 0 {
-			gologger.Info().Msgf("Filter Status Code : %s%v%s", config.Yellow, config.Cfg.FilterStatus, config.Reset)
-		}
-		if len(config.Cfg.FilterStrings) != 0 {
-			gologger.Info().Msgf("Filter Strings : %s%v%s", config.Yellow, config.Cfg.FilterStrings, config.Reset)
-		}
-		if len(config.Cfg.FilterContentSize) != 0 {
-			gologger.Info().Msgf("Filter ContentSize : %s%v%s", config.Yellow, config.Cfg.FilterContentSize, config.Reset)
-		}
-		if config.Cfg.WebCache {
-			gologger.Info().Msgf("Detect Web Cache : %sEnabled%s", config.Yellow, config.Reset)
+		for _, field := range ScanInfo() {
+			gologger.Info().Msgf("%s : %s%s%s", field.Name, config.Yellow, field.Value, config.Reset)
 		}
 	}
 	fmt.Println("----------------------------------------------------------------")
@@ -176,7 +309,7 @@ func Progbar(progNum int) *progressbar.ProgressBar {
 	bar := progressbar.NewOptions(progNum,
 		progressbar.OptionSetWriter(os.Stderr),
 		progressbar.OptionSetWidth(30),
-		progressbar.OptionSetDescription("\r\033[KProcessing"),
+		progressbar.OptionSetDescription(BarDescription(config.Cfg.Rate)),
 		progressbar.OptionSetRenderBlankState(true),
 		progressbar.OptionShowIts(),
 		progressbar.OptionShowCount(),
@@ -191,109 +324,51 @@ func Progbar(progNum int) *progressbar.ProgressBar {
 	return bar
 }
 
-// processResult handles the result of an HTTP request
-func ProcessResult(result *config.Result, cfg config.Config) {
-	var mS string  // Match Status Code
-	var mCS string // Match Content Size
-
-	var fS string  // Filter Status Code
-	var fCS string // Filter Content Size
-
-	mSCode := cfg.MatchStatus      // slice Match Status Code
-	mCSize := cfg.MatchContentSize // slice Match Content Size
-
-	fSCode := cfg.FilterStatus      // slice Filter Status Code
-	fCSize := cfg.FilterContentSize // slice Filter Content Size
-
-	// allF := len(cfg.FilterStatus) != 0 && len(cfg.FilterStrings) != 0 && len(cfg.FilterContentSize) != 0
-	zeroF := len(cfg.FilterStatus) == 0 && len(cfg.FilterStrings) == 0 && len(cfg.FilterContentSize) == 0
-	// statusF := len(cfg.FilterStatus) != 0 && len(cfg.FilterStrings) == 0 && len(cfg.FilterContentSize) == 0
-	// sizeF := len(cfg.FilterStatus) == 0 && len(cfg.FilterStrings) == 0 && len(cfg.FilterContentSize) != 0
-	// stringF := len(cfg.FilterStatus) == 0 && len(cfg.FilterStrings) != 0 && len(cfg.FilterContentSize) == 0
-	// status_stringF := len(cfg.FilterStatus) != 0 && len(cfg.FilterStrings) != 0 && len(cfg.FilterContentSize) == 0
-	// status_sizeF := len(cfg.FilterStatus) != 0 && len(cfg.FilterStrings) == 0 && len(cfg.FilterContentSize) != 0
-	// size_stringF := len(cfg.FilterStatus) == 0 && len(cfg.FilterStrings) != 0 && len(cfg.FilterContentSize) != 0
+// BarDescription returns the description of the progress bar, with the -rate limit if there is one
+func BarDescription(rate int) string {
+	if rate > 0 {
+		return fmt.Sprintf("\r\033[KProcessing [rate %d/s]", rate)
+	}
+	return "\r\033[KProcessing"
+}
 
-	// allM := len(cfg.MatchStatus) != 0 && len(cfg.MatchStrings) != 0 && len(cfg.MatchContentSize) != 0
-	zeroM := len(cfg.MatchStatus) == 0 && len(cfg.MatchStrings) == 0 && len(cfg.MatchContentSize) == 0
-	// statusM := len(cfg.MatchStatus) != 0 && len(cfg.MatchStrings) == 0 && len(cfg.MatchContentSize) == 0
-	// sizeM := len(cfg.MatchStatus) == 0 && len(cfg.MatchStrings) == 0 && len(cfg.MatchContentSize) != 0
-	// stringM := len(cfg.MatchStatus) == 0 && len(cfg.MatchStrings) != 0 && len(cfg.MatchContentSize) == 0
-	// status_stringM := len(cfg.MatchStatus) != 0 && len(cfg.MatchStrings) != 0 && len(cfg.MatchContentSize) == 0
-	// status_sizeM := len(cfg.MatchStatus) != 0 && len(cfg.MatchStrings) == 0 && len(cfg.MatchContentSize) != 0
-	// size_stringM := len(cfg.MatchStatus) == 0 && len(cfg.MatchStrings) != 0 && len(cfg.MatchContentSize) != 0
+// CloseFiles flushes and closes the output files
+func CloseFiles() {
+	config.Mu.Lock()
+	defer config.Mu.Unlock()
 
-	if len(cfg.MatchStatus) == 0 && len(cfg.MatchStrings) == 0 && len(cfg.MatchContentSize) == 0 && zeroF {
-		MatchPrintOut(result, mS, mCS)
-	} else if len(cfg.MatchStrings) != 0 && len(cfg.MatchStatus) == 0 && len(cfg.MatchContentSize) == 0 && zeroF {
-		MatchPrintOut(result, mS, mCS)
-	} else if len(cfg.MatchStatus) != 0 && len(cfg.MatchStrings) == 0 && len(cfg.MatchContentSize) == 0 && zeroF {
-		for _, mSCodes := range mSCode {
-			MatchPrintOut(result, mSCodes, mCS)
-		}
-	} else if len(cfg.MatchStatus) != 0 && len(cfg.MatchStrings) != 0 && len(cfg.MatchContentSize) == 0 && zeroF {
-		for _, mSCodes := range mSCode {
-			MatchPrintOut(result, mSCodes, mCS)
-		}
-	} else if len(cfg.MatchStatus) == 0 && len(cfg.MatchStrings) != 0 && len(cfg.MatchContentSize) != 0 && zeroF {
-		for _, mCSizes := range mCSize {
-			MatchPrintOut(result, mS, mCSizes)
-		}
-	} else if len(cfg.MatchStatus) == 0 && len(cfg.MatchStrings) == 0 && len(cfg.MatchContentSize) != 0 && zeroF {
-		for _, mCSizes := range mCSize {
-			MatchPrintOut(result, mS, mCSizes)
-		}
-	} else if len(cfg.MatchStatus) != 0 && len(cfg.MatchContentSize) != 0 && len(cfg.MatchStrings) == 0 && zeroF {
-		for _, mSCodes := range mSCode {
-			for _, mCSizes := range mCSize {
-				MatchPrintOut(result, mSCodes, mCSizes)
-			}
-		}
-	} else if len(cfg.MatchStatus) != 0 && len(cfg.MatchContentSize) != 0 && len(cfg.MatchStrings) != 0 && zeroF {
-		for _, mSCodes := range mSCode {
-			for _, mCSizes := range mCSize {
-				MatchPrintOut(result, mSCodes, mCSizes)
-			}
+	if outputWriter != nil {
+		if err := outputWriter.Close(); err != nil {
+			gologger.Error().Msgf("Error writing To success file: %v", err)
 		}
+		outputWriter = nil
 	}
-
-	if len(cfg.FilterStrings) != 0 && len(cfg.FilterStatus) == 0 && len(cfg.FilterContentSize) == 0 && zeroM {
-		FilterPrintOut(result, fS, fCS)
-	} else if len(cfg.FilterStatus) != 0 && len(cfg.FilterStrings) == 0 && len(cfg.FilterContentSize) == 0 && zeroM {
-		for _, fSCodes := range fSCode {
-			FilterPrintOut(result, fSCodes, fCS)
+	for _, file := range []*os.File{config.Cfg.SuccessFile, config.Cfg.Cachefile} {
+		if file == nil {
+			continue
 		}
-	} else if len(cfg.FilterStatus) != 0 && len(cfg.FilterStrings) != 0 && len(cfg.FilterContentSize) == 0 && zeroM {
-		for _, fSCodes := range fSCode {
-			FilterPrintOut(result, fSCodes, fCS)
+		if err := file.Sync(); err != nil {
+			gologger.Error().Msgf("Error flushing %s: %v", file.Name(), err)
 		}
-	} else if len(cfg.FilterStatus) == 0 && len(cfg.FilterStrings) != 0 && len(cfg.FilterContentSize) != 0 && zeroM {
-		for _, fCSizes := range fCSize {
-			FilterPrintOut(result, fS, fCSizes)
-		}
-	} else if len(cfg.FilterStatus) == 0 && len(cfg.FilterStrings) == 0 && len(cfg.FilterContentSize) != 0 && zeroM {
-		for _, fCSizes := range fCSize {
-			FilterPrintOut(result, fS, fCSizes)
-		}
-	} else if len(cfg.FilterStatus) != 0 && len(cfg.FilterContentSize) != 0 && len(cfg.FilterStrings) == 0 && zeroM {
-		for _, fSCodes := range fSCode {
-			for _, fCSizes := range fCSize {
-				FilterPrintOut(result, fSCodes, fCSizes)
-			}
-		}
-	} else if len(cfg.FilterStatus) != 0 && len(cfg.FilterContentSize) != 0 && len(cfg.FilterStrings) != 0 && zeroM {
-		for _, fSCodes := range fSCode {
-			for _, fCSizes := range fCSize {
-				FilterPrintOut(result, fSCodes, fCSizes)
-			}
+		if err := file.Close(); err != nil {
+			gologger.Error().Msgf("Error closing %s: %v", file.Name(), err)
 		}
 	}
+	config.Cfg.SuccessFile = nil
+	config.Cfg.Cachefile = nil
+}
 
-	// if statusM && statusF {
-	// 	for _, mSCodes := range mSCode {
-	// 		for _, fSCodes := range fSCode {
-	// 			printOut(result, mSCodes, mCS, fSCodes, fCS, statusM, statusF)
-	// 		}
-	// 	}
-	// }
+// PrintSummary prints the request, match and error counts of the run
+func PrintSummary(elapsed time.Duration, interrupted bool) {
+	if config.Cfg.Silent {
+		return
+	}
+	if interrupted {
+		gologger.Info().Msgf("%sInterrupted, the remaining requests were not sent%s", config.Red, config.Reset)
+	}
+	gologger.Info().Msgf("Requests : %s%d%s, Matches : %s%d%s, Errors : %s%d%s, Duration : %s%v%s",
+		config.Yellow, config.Stat.Requests.Load(), config.Reset,
+		config.Yellow, config.Stat.Matches.Load(), config.Reset,
+		config.Yellow, config.Stat.Errors.Load(), config.Reset,
+		config.Yellow, elapsed.Round(time.Millisecond), config.Reset)
 }
@@ -0,0 +1,269 @@
+package opt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/projectdiscovery/gologger"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+)
+
+// combinationStream streams keyword/word sets To a channel
+type combinationStream struct {
+	ctx  context.Context
+	out  chan map[string]string
+	skip int64 // skip is the number of sets still To read without sending them.
+}
+
+// Combinations streams the keyword/word sets for the wordlists according To the mode.
+// Words are read from disk as they are needed, so memory does not grow with the wordlists.
+func Combinations(ctx context.Context, wordlists []config.Wordlist, mode string) <-chan map[string]string {
+	return CombinationsFrom(ctx, wordlists, mode, 0)
+}
+
+// CombinationsFrom streams the keyword/word sets like Combinations, starting at the given one.
+// The skipped sets are read but not copied.
+func CombinationsFrom(ctx context.Context, wordlists []config.Wordlist, mode string, start int64) <-chan map[string]string {
+	stream := &combinationStream{ctx: ctx, out: make(chan map[string]string, 64), skip: start}
+
+	go func() {
+		defer close(stream.out)
+		if len(wordlists) == 0 {
+			return
+		}
+
+		switch mode {
+		case config.ModePitchfork:
+			stream.pitchfork(wordlists)
+		case config.ModeSniper:
+			stream.sniper(wordlists)
+		default:
+			stream.clusterbomb(wordlists, make(map[string]string, len(wordlists)))
+		}
+	}()
+
+	return stream.out
+}
+
+// CountCombinations returns the number of keyword/word sets Combinations streams
+func CountCombinations(wordlists []config.Wordlist, mode string) int {
+	if len(wordlists) == 0 {
+		return 0
+	}
+
+	switch mode {
+	case config.ModePitchfork:
+		return shortestWordlist(wordlists)
+	case config.ModeSniper:
+		// The first word of a wordlist is its default, the set of all the defaults is only sent once
+		total, defaults := 0, false
+		for _, wordlist := range wordlists {
+			if wordlist.Count == 0 {
+				continue
+			}
+			total += wordlist.Count
+			if defaults {
+				total--
+			}
+			defaults = true
+		}
+		return total
+	default:
+		total := 1
+		for _, wordlist := range wordlists {
+			total *= wordlist.Count
+		}
+		return total
+	}
+}
+
+// clusterbomb streams every combination of words across the wordlists (cartesian product).
+// The inner wordlists are read again for every word of the outer ones.
+func (s *combinationStream) clusterbomb(wordlists []config.Wordlist, inputs map[string]string) bool {
+	wordlist := wordlists[0]
+	ok := true
+	err := ScanWords(wordlist.Path, func(word string) bool {
+		inputs[wordlist.Keyword] = word
+		if len(wordlists) == 1 {
+			ok = s.emit(inputs)
+		} else {
+			ok = s.clusterbomb(wordlists[1:], inputs)
+		}
+		return ok
+	})
+	if err != nil {
+		gologger.Error().Msgf("Error reading wordlist %s: %v", wordlist.Path, err)
+		return false
+	}
+	return ok
+}
+
+// pitchfork pairs the n-th word of every wordlist, stopping at the shortest wordlist
+func (s *combinationStream) pitchfork(wordlists []config.Wordlist) {
+	scanners := make([]*bufio.Scanner, len(wordlists))
+	for i, wordlist := range wordlists {
+		file, err := os.Open(wordlist.Path)
+		if err != nil {
+			gologger.Error().Msgf("Error reading wordlist %s: %v", wordlist.Path, err)
+			return
+		}
+		defer file.Close()
+		scanners[i] = bufio.NewScanner(file)
+	}
+
+	inputs := make(map[string]string, len(wordlists))
+	for {
+		for i, scanner := range scanners {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					gologger.Error().Msgf("Error reading wordlist %s: %v", wordlists[i].Path, err)
+				}
+				return
+			}
+			inputs[wordlists[i].Keyword] = scanner.Text()
+		}
+		if !s.emit(inputs) {
+			return
+		}
+	}
+}
+
+// sniper fuzzes one keyword at a time while every other keyword keeps its default,
+// which is the first word of its wordlist. The first word of each wordlist gives the
+// set of all the defaults, so it is only sent by the first wordlist with words.
+func (s *combinationStream) sniper(wordlists []config.Wordlist) {
+	defaults, err := FirstWords(wordlists)
+	if err != nil {
+		gologger.Error().Msgf("Error reading wordlist: %v", err)
+		return
+	}
+
+	sent := false
+	for _, wordlist := range wordlists {
+		inputs := make(map[string]string, len(defaults))
+		for keyword, value := range defaults {
+			inputs[keyword] = value
+		}
+		ok, first := true, true
+		err := ScanWords(wordlist.Path, func(word string) bool {
+			if first {
+				first = false
+				if sent {
+					return true
+				}
+				sent = true
+			}
+			inputs[wordlist.Keyword] = word
+			ok = s.emit(inputs)
+			return ok
+		})
+		if err != nil {
+			gologger.Error().Msgf("Error reading wordlist %s: %v", wordlist.Path, err)
+			return
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// FirstWords returns the first word of every wordlist, keyed by keyword
+func FirstWords(wordlists []config.Wordlist) (map[string]string, error) {
+	words := make(map[string]string, len(wordlists))
+	for _, wordlist := range wordlists {
+		words[wordlist.Keyword] = ""
+		err := ScanWords(wordlist.Path, func(word string) bool {
+			words[wordlist.Keyword] = word
+			return false
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", wordlist.Path, err)
+		}
+	}
+	return words, nil
+}
+
+// emit sends a copy of the inputs, it returns false once the context is canceled
+func (s *combinationStream) emit(inputs map[string]string) bool {
+	if s.skip > 0 {
+		s.skip--
+		return s.ctx.Err() == nil
+	}
+
+	combo := make(map[string]string, len(inputs))
+	for keyword, word := range inputs {
+		combo[keyword] = word
+	}
+
+	select {
+	case s.out <- combo:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// shortestWordlist returns the number of words in the shortest wordlist
+func shortestWordlist(wordlists []config.Wordlist) int {
+	shortest := wordlists[0].Count
+	for _, wordlist := range wordlists[1:] {
+		if wordlist.Count < shortest {
+			shortest = wordlist.Count
+		}
+	}
+	return shortest
+}
+
+// ScanWords calls fn for every line of the file, until fn returns false
+func ScanWords(filename string, fn func(word string) bool) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if !fn(scanner.Text()) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// CountLines counts the lines of a file without keeping them in memory
+func CountLines(filename string) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	last := byte('\n')
+	buf := make([]byte, 256*1024)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			count += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// The last line may not end with a newline
+	if last != '\n' {
+		count++
+	}
+	return count, nil
+}
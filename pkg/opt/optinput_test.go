@@ -0,0 +1,79 @@
+package opt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+)
+
+// writeWordlist writes the words To a file, one per line, and returns the wordlist bound To the keyword
+func writeWordlist(t *testing.T, keyword string, words ...string) config.Wordlist {
+	path := filepath.Join(t.TempDir(), keyword+".txt")
+	content := strings.Join(words, "\n")
+	if len(words) > 0 {
+		content += "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	count, err := CountLines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.Wordlist{Path: path, Keyword: keyword, Count: count}
+}
+
+func TestCombinations(t *testing.T) {
+	users := writeWordlist(t, "USER", "admin", "root")
+	passes := writeWordlist(t, "PASS", "123", "pass", "toor")
+	empty := writeWordlist(t, "EMPTY")
+
+	tests := []struct {
+		name      string
+		mode      string
+		wordlists []config.Wordlist
+		want      []string // want are the combinations as USER/PASS, in order
+	}{
+		{"clusterbomb", config.ModeClusterbomb, []config.Wordlist{users, passes}, []string{
+			"admin/123", "admin/pass", "admin/toor", "root/123", "root/pass", "root/toor",
+		}},
+		{"pitchfork", config.ModePitchfork, []config.Wordlist{users, passes}, []string{"admin/123", "root/pass"}},
+		{"sniper sends the defaults once", config.ModeSniper, []config.Wordlist{users, passes}, []string{
+			"admin/123", "root/123", "admin/pass", "admin/toor",
+		}},
+		{"sniper after an empty wordlist", config.ModeSniper, []config.Wordlist{empty, users, passes}, []string{
+			"admin/123", "root/123", "admin/pass", "admin/toor",
+		}},
+		{"sniper single wordlist", config.ModeSniper, []config.Wordlist{passes}, []string{"/123", "/pass", "/toor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for inputs := range Combinations(context.Background(), tt.wordlists, tt.mode) {
+				got = append(got, fmt.Sprintf("%s/%s", inputs["USER"], inputs["PASS"]))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Combinations() = %q, want %q", got, tt.want)
+			}
+			if count := CountCombinations(tt.wordlists, tt.mode); count != len(tt.want) {
+				t.Errorf("CountCombinations() = %d, want %d", count, len(tt.want))
+			}
+
+			// Starting part way gives the rest of the sets
+			start := len(tt.want) / 2
+			got = nil
+			for inputs := range CombinationsFrom(context.Background(), tt.wordlists, tt.mode, int64(start)) {
+				got = append(got, fmt.Sprintf("%s/%s", inputs["USER"], inputs["PASS"]))
+			}
+			if !slices.Equal(got, tt.want[start:]) {
+				t.Errorf("CombinationsFrom(%d) = %q, want %q", start, got, tt.want[start:])
+			}
+		})
+	}
+}
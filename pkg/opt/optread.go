@@ -6,14 +6,14 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/projectdiscovery/gologger"
 
-	"github.com/SpeedyQweku/qfuzz/pkg/config"
 	"github.com/SpeedyQweku/qfuzz/pkg/common"
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
 )
 
-
 // It reads the response body
 func ReadResponseBody(resp *http.Response, fullUrl string) ([]byte, error) {
 	var bodyBuffer bytes.Buffer
@@ -25,16 +25,36 @@ func ReadResponseBody(resp *http.Response, fullUrl string) ([]byte, error) {
 	return bodyBuffer.Bytes(), nil
 }
 
-// readInputFiles reads the wordlist and URLs from specified files
-func ReadInputFiles(cfg config.Config) ([]string, []string) {
-	var words, urls []string
+// BodyWords counts the words of the response body
+func BodyWords(body []byte) int {
+	return len(bytes.Fields(body))
+}
+
+// BodyLines counts the lines of the response body, the last one may not end with a newline
+func BodyLines(body []byte) int {
+	if len(body) == 0 {
+		return 0
+	}
+	lines := bytes.Count(body, []byte{'\n'})
+	if body[len(body)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+// readInputFiles reads the wordlists and URLs from specified files
+func ReadInputFiles(cfg config.Config) ([]config.Wordlist, []string) {
+	var wordlists []config.Wordlist
+	var urls []string
 	var err error
 
-	if cfg.WordlistFile != "" {
-		words, err = ReadLines(cfg.WordlistFile)
+	for _, item := range cfg.Wordlists {
+		wordlist := ParseWordlist(item)
+		wordlist.Count, err = CountLines(wordlist.Path)
 		if err != nil {
-			gologger.Fatal().Msgf("Error reading wordlist: %v", err)
+			gologger.Fatal().Msgf("Error reading wordlist %s: %v", wordlist.Path, err)
 		}
+		wordlists = append(wordlists, wordlist)
 	}
 
 	if cfg.UrlFile != "" {
@@ -46,7 +66,19 @@ func ReadInputFiles(cfg config.Config) ([]string, []string) {
 		urls = cfg.UrlString
 	}
 
-	return words, urls
+	return wordlists, urls
+}
+
+// ParseWordlist splits a -w value into its file path and keyword (path:KEYWORD)
+func ParseWordlist(value string) config.Wordlist {
+	if i := strings.LastIndex(value, ":"); i > 0 {
+		keyword := value[i+1:]
+		// A colon followed by a path separator belongs To the path itself (e.g., C:\words.txt)
+		if keyword != "" && !strings.ContainsAny(keyword, `/\.`) {
+			return config.Wordlist{Path: value[:i], Keyword: keyword}
+		}
+	}
+	return config.Wordlist{Path: value, Keyword: config.Keyword}
 }
 
 // Reading A File Line By Line
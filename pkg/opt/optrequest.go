@@ -0,0 +1,148 @@
+package opt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/rawhttp"
+)
+
+// RawRequest is an HTTP request read from a raw request file
+type RawRequest struct {
+	Method  string   // Method is the HTTP method of the request line.
+	Target  string   // Target is the request target (path and query) of the request line.
+	Host    string   // Host is the value of the Host header.
+	Headers []string // Headers are the "Name: value" header lines, in their original order and casing.
+	Body    string   // Body is everything after the blank line ending the headers.
+}
+
+// ReadRequestFile loads the raw request file into the configuration and returns the target URLs.
+// When targets are given with -u/-l, only their scheme and host are used.
+func ReadRequestFile(cfg *config.Config, urls []string) []string {
+	file, err := os.Open(cfg.RequestFile)
+	if err != nil {
+		gologger.Fatal().Msgf("Error reading request file: %v", err)
+	}
+	defer file.Close()
+
+	raw, err := ParseRawRequest(file)
+	if err != nil {
+		gologger.Fatal().Msgf("Error parsing request file %s: %v", cfg.RequestFile, err)
+	}
+
+	cfg.HttpMethod = raw.Method
+	cfg.PostData = raw.Body
+
+	if len(urls) == 0 {
+		cfg.Headers = append(raw.Headers, cfg.Headers...)
+		if raw.Host == "" {
+			gologger.Fatal().Msgf("%sRequest file has no Host header, specify target using -l or -u%s", config.Red, config.Reset)
+		}
+		return []string{fmt.Sprintf("%s://%s%s", cfg.RequestProto, raw.Host, raw.Target)}
+	}
+
+	// The targets replace the host, the Host header of the file is dropped so it does not override them
+	headers := make([]string, 0, len(raw.Headers)+len(cfg.Headers))
+	for _, header := range raw.Headers {
+		if name, _, _ := strings.Cut(header, ":"); !strings.EqualFold(strings.TrimSpace(name), "Host") {
+			headers = append(headers, header)
+		}
+	}
+	cfg.Headers = append(headers, cfg.Headers...)
+
+	targets := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if !strings.Contains(url, "://") {
+			url = "https://" + url
+		}
+		target, err := neturl.Parse(url)
+		if err != nil {
+			gologger.Error().Msgf(config.Red + "Invalid URL: " + url + config.Reset)
+			continue
+		}
+		targets = append(targets, fmt.Sprintf("%s://%s%s", target.Scheme, target.Host, raw.Target))
+	}
+	return targets
+}
+
+// SetRawTransport sends the requests of the client over HTTP/1.1 with the headers of the
+// raw request file in their order and casing, net/http would sort them
+func SetRawTransport(client *http.Client) {
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		client.Transport = &rawhttp.Transport{Base: transport}
+	}
+}
+
+// ParseRawRequest parses a raw HTTP/1.x request (request line, headers and body)
+func ParseRawRequest(r io.Reader) (RawRequest, error) {
+	var raw RawRequest
+	reader := bufio.NewReader(r)
+
+	line, err := readRawLine(reader)
+	if err != nil {
+		return raw, fmt.Errorf("missing request line: %w", err)
+	}
+	parts := strings.Fields(line)
+	if len(parts) < 2 {
+		return raw, fmt.Errorf("malformed request line: %q", line)
+	}
+	raw.Method = parts[0]
+	raw.Target = parts[1]
+
+	// An absolute-form target (as sent To proxies) carries the host itself
+	if strings.Contains(raw.Target, "://") {
+		target, err := neturl.Parse(raw.Target)
+		if err != nil {
+			return raw, fmt.Errorf("malformed request target: %w", err)
+		}
+		raw.Host = target.Host
+		raw.Target = target.RequestURI()
+	}
+
+	for {
+		line, err := readRawLine(reader)
+		if err != nil && line == "" {
+			if err == io.EOF {
+				return raw, nil
+			}
+			return raw, err
+		}
+		if line == "" {
+			break
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			return raw, fmt.Errorf("malformed header: %q", line)
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+
+		// The body may change length once fuzzed, Content-Length keeps its place but its value is recomputed when sent
+		if strings.EqualFold(name, "Host") {
+			raw.Host = value
+		}
+		raw.Headers = append(raw.Headers, name+": "+value)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return raw, err
+	}
+	raw.Body = string(body)
+	return raw, nil
+}
+
+// readRawLine reads a line without its trailing CRLF or LF
+func readRawLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
@@ -0,0 +1,108 @@
+package opt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+)
+
+func TestParseRawRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    RawRequest
+		wantErr bool
+	}{
+		{
+			name: "get",
+			raw:  "GET /FUZZ?q=1 HTTP/1.1\r\nHost: example.com\r\nX-Token: abc\r\n\r\n",
+			want: RawRequest{Method: "GET", Target: "/FUZZ?q=1", Host: "example.com", Headers: []string{"Host: example.com", "X-Token: abc"}},
+		},
+		{
+			name: "post with body",
+			raw:  "POST /login HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 17\r\n\r\n{\"user\":\"FUZZ\"}\r\n",
+			want: RawRequest{Method: "POST", Target: "/login", Host: "example.com", Headers: []string{"Host: example.com", "Content-Type: application/json", "Content-Length: 17"}, Body: "{\"user\":\"FUZZ\"}\r\n"},
+		},
+		{
+			name: "lf line endings",
+			raw:  "PUT /a HTTP/1.1\nhost: example.com:8080\nx-lower: kept\n\nbody",
+			want: RawRequest{Method: "PUT", Target: "/a", Host: "example.com:8080", Headers: []string{"host: example.com:8080", "x-lower: kept"}, Body: "body"},
+		},
+		{
+			name: "absolute form target",
+			raw:  "GET http://proxy.example.com/path?x=1 HTTP/1.1\r\n\r\n",
+			want: RawRequest{Method: "GET", Target: "/path?x=1", Host: "proxy.example.com"},
+		},
+		{
+			name: "no blank line",
+			raw:  "GET / HTTP/1.1\r\nHost: example.com",
+			want: RawRequest{Method: "GET", Target: "/", Host: "example.com", Headers: []string{"Host: example.com"}},
+		},
+		{
+			name: "value with colon",
+			raw:  "GET / HTTP/1.1\r\nReferer: https://example.com/\r\n\r\n",
+			want: RawRequest{Method: "GET", Target: "/", Headers: []string{"Referer: https://example.com/"}},
+		},
+		{name: "empty", raw: "", wantErr: true},
+		{name: "malformed request line", raw: "GET\r\n\r\n", wantErr: true},
+		{name: "malformed header", raw: "GET / HTTP/1.1\r\nnot a header\r\n\r\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRawRequest(strings.NewReader(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRawRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRawRequest() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadRequestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "req.txt")
+	raw := "POST /api/FUZZ HTTP/1.1\r\nHost: file.example.com\r\nCookie: a=b\r\n\r\nid=1"
+	if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		urls        []string
+		wantTargets []string
+		wantHeaders []string
+	}{
+		{
+			name:        "host of the file",
+			wantTargets: []string{"https://file.example.com/api/FUZZ"},
+			wantHeaders: []string{"Host: file.example.com", "Cookie: a=b", "X-Extra: 1"},
+		},
+		{
+			name:        "targets replace the host",
+			urls:        []string{"http://127.0.0.1:8080/ignored", "target.example.com"},
+			wantTargets: []string{"http://127.0.0.1:8080/api/FUZZ", "https://target.example.com/api/FUZZ"},
+			wantHeaders: []string{"Cookie: a=b", "X-Extra: 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{RequestFile: path, RequestProto: "https", Headers: []string{"X-Extra: 1"}}
+			targets := ReadRequestFile(cfg, tt.urls)
+			if !slices.Equal(targets, tt.wantTargets) {
+				t.Errorf("targets = %q, want %q", targets, tt.wantTargets)
+			}
+			if !slices.Equal(cfg.Headers, tt.wantHeaders) {
+				t.Errorf("headers = %q, want %q", cfg.Headers, tt.wantHeaders)
+			}
+			if cfg.HttpMethod != "POST" || cfg.PostData != "id=1" {
+				t.Errorf("method, body = %q, %q, want POST, id=1", cfg.HttpMethod, cfg.PostData)
+			}
+		})
+	}
+}
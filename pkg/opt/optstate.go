@@ -0,0 +1,216 @@
+package opt
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+)
+
+// DefaultStateFile is where the state is saved on interrupt when -save-state is not given
+const DefaultStateFile = "qfuzz-resume.json"
+
+// State is a snapshot of a scan, saved To a file so the scan can be resumed
+type State struct {
+	Version   string        `json:"version"`   // Version is the qfuzz version that saved the state.
+	SavedAt   time.Time     `json:"saved_at"`  // SavedAt is when the state was saved.
+	Config    config.Config `json:"config"`    // Config is the configuration of the scan.
+	Completed int64         `json:"completed"` // Completed is the number of requests that are all done.
+	Progress  []int64       `json:"progress"`  // Progress is the number of combinations all done for each target URL, in order.
+	Requests  int64         `json:"requests"`  // Requests is the number of requests sent so far.
+	Errors    int64         `json:"errors"`    // Errors is the number of failed requests so far.
+	Findings  string        `json:"findings"`  // Findings is the file of the findings so far, next To the state file.
+}
+
+// finding is a line of the findings file, the result with its position in the run
+type finding struct {
+	Job int64 `json:"job"`
+	config.Result
+}
+
+var (
+	// StateConfig is the configuration saved in the state, before the raw request file is applied
+	StateConfig config.Config
+
+	findingsFile *os.File
+	findingsErr  error
+	findingsMu   sync.Mutex
+)
+
+// FindingsPath returns the findings file kept next To a state file
+func FindingsPath(statePath string) string {
+	return statePath + ".findings"
+}
+
+// RecordFinding appends the result To the findings file, so an interrupted scan saves its
+// findings so far without holding them all in memory
+func RecordFinding(result *config.Result) {
+	findingsMu.Lock()
+	defer findingsMu.Unlock()
+	if findingsFile == nil {
+		if findingsErr != nil {
+			return
+		}
+		findingsFile, findingsErr = os.Create(FindingsPath(StatePath()))
+		if findingsErr != nil {
+			gologger.Error().Msgf("Error creating findings file: %v", findingsErr)
+			return
+		}
+	}
+	data, err := json.Marshal(finding{Job: result.Job, Result: *result})
+	if err != nil {
+		return
+	}
+	if _, err := findingsFile.Write(append(data, '\n')); err != nil {
+		gologger.Error().Msgf("Error writing findings file %s: %v", findingsFile.Name(), err)
+	}
+}
+
+// RemoveFindings closes and removes the findings file, once there is nothing left To resume
+func RemoveFindings() {
+	findingsMu.Lock()
+	defer findingsMu.Unlock()
+	if findingsFile != nil {
+		findingsFile.Close()
+		os.Remove(findingsFile.Name())
+		findingsFile = nil
+	}
+}
+
+// SaveState writes the state of the scan, progress is the number of combinations all done for each target URL
+func SaveState(path string, progress []int64) error {
+	state := State{
+		Version:  config.Version,
+		SavedAt:  time.Now(),
+		Config:   StateConfig,
+		Progress: progress,
+		Requests: config.Stat.Requests.Load(),
+		Errors:   config.Stat.Errors.Load(),
+	}
+	for _, done := range progress {
+		state.Completed += done
+	}
+	findingsMu.Lock()
+	if findingsFile != nil {
+		state.Findings = findingsFile.Name()
+	}
+	findingsMu.Unlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write To a temporary file first, so an interrupted save keeps the previous state
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadState reads a state file, and replaces the configuration with the saved one
+func LoadState(path string) State {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		gologger.Fatal().Msgf("Error reading state file: %v", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		gologger.Fatal().Msgf("Error parsing state file %s: %v", path, err)
+	}
+	if state.Version == "" {
+		gologger.Fatal().Msgf("%sNot a qfuzz state file: %s%s", config.Red, path, config.Reset)
+	}
+
+	config.Cfg = state.Config
+	config.Cfg.Resume = path
+	config.UserAgents()
+
+	config.Stat.Requests.Store(state.Requests)
+	config.Stat.Errors.Store(state.Errors)
+	return state
+}
+
+// Done reports whether the job was completed before the state was saved
+func (s State) Done(job int64) bool {
+	urls := int64(len(s.Progress))
+	return urls > 0 && job/urls < s.Progress[job%urls]
+}
+
+// RestoreFindings writes the findings of a resumed scan back To the output file, and into the
+// findings file of the new run. The findings of the jobs not done are found again.
+func RestoreFindings(state State) {
+	restored := 0
+	if state.Findings != "" {
+		path := state.Findings
+		// The findings file is written again from the start, read the saved one aside
+		if path == FindingsPath(StatePath()) {
+			path += ".old"
+			if err := os.Rename(state.Findings, path); err != nil && !os.IsNotExist(err) {
+				gologger.Fatal().Msgf("Error reading findings file: %v", err)
+			}
+			defer os.Remove(path)
+		}
+
+		file, err := os.Open(path)
+		if err != nil && !os.IsNotExist(err) {
+			gologger.Fatal().Msgf("Error reading findings file: %v", err)
+		}
+		if err == nil {
+			scanner := bufio.NewScanner(file)
+			scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+			for scanner.Scan() {
+				var saved finding
+				if err := json.Unmarshal(scanner.Bytes(), &saved); err != nil || !state.Done(saved.Job) {
+					continue
+				}
+				saved.Result.Job = saved.Job
+				RecordFinding(&saved.Result)
+				config.Stat.Matches.Add(1)
+				SaveResult(&saved.Result)
+				restored++
+			}
+			file.Close()
+		}
+	}
+	if !config.Cfg.Silent {
+		gologger.Info().Msgf("Resuming %s at request %s%d%s with %s%d%s findings", config.Cfg.Resume, config.Yellow, state.Completed, config.Reset, config.Yellow, restored, config.Reset)
+	}
+}
+
+// StatePath returns where the state is saved, -save-state first then the resumed file
+func StatePath() string {
+	if config.Cfg.SaveState != "" {
+		return config.Cfg.SaveState
+	} else if config.Cfg.Resume != "" {
+		return config.Cfg.Resume
+	}
+	return DefaultStateFile
+}
+
+// PrintSaveState saves the state on interrupt, and tells how To resume
+func PrintSaveState(progress []int64) {
+	path := StatePath()
+	if err := SaveState(path, progress); err != nil {
+		gologger.Error().Msgf("Error saving state file %s: %v", path, err)
+		return
+	}
+	gologger.Info().Msgf("State saved, resume with: %sqfuzz -resume %s%s", config.Yellow, path, config.Reset)
+}
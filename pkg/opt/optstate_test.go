@@ -0,0 +1,88 @@
+package opt
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+	"github.com/SpeedyQweku/qfuzz/pkg/matcher"
+)
+
+// find records and saves the finding of the job, as PrintResult does
+func find(job int64) {
+	result := &config.Result{StatusCode: 200, URL: fmt.Sprintf("https://example.com/%d", job), Job: job}
+	RecordFinding(result)
+	SaveResult(result)
+}
+
+func TestResumeFindings(t *testing.T) {
+	// Run in a temporary directory, where the state is saved without -save-state
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		config.Cfg = config.Config{}
+		Matchers = nil
+	})
+	outputFile := filepath.Join(dir, "out.jsonl")
+
+	// The first run has two target URLs, jobs alternate between them. It finds jobs 1, 3 and 4,
+	// and is interrupted with three combinations of the first URL done and one of the second,
+	// so jobs 0, 1, 2 and 4 are done, without -save-state
+	config.Cfg = config.Config{OutputFile: outputFile, OutputFormat: "jsonl"}
+	StateConfig = config.Cfg
+	Matchers = &matcher.Engine{Default: true}
+	OpenOutputFile()
+	for _, job := range []int64{1, 3, 4} {
+		find(job)
+	}
+	PrintSaveState([]int64{3, 1})
+	CloseFiles()
+	findingsFile = nil
+
+	// The resumed run finds job 3 again and job 7, then completes
+	state := LoadState(DefaultStateFile)
+	OpenOutputFile()
+	RestoreFindings(state)
+	for _, job := range []int64{3, 7} {
+		find(job)
+	}
+	CloseFiles()
+	RemoveFindings()
+
+	file, err := os.Open(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var urls []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var result config.Result
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, result.URL)
+	}
+	want := []string{"https://example.com/1", "https://example.com/4", "https://example.com/3", "https://example.com/7"}
+	if !slices.Equal(urls, want) {
+		t.Errorf("output file = %q, want %q", urls, want)
+	}
+	if state.Completed != 4 {
+		t.Errorf("completed = %d, want 4", state.Completed)
+	}
+	if _, err := os.Stat(FindingsPath(DefaultStateFile)); !os.IsNotExist(err) {
+		t.Errorf("findings file kept after the scan completed: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package output
+
+import (
+	"encoding/csv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+)
+
+// csvHeader is the first row of the CSV output
+var csvHeader = []string{"url", "status", "length", "words", "lines", "method", "input", "redirect_location", "content_type", "captures", "cluster", "duration_ms", "timestamp"}
+
+// csvWriter writes each result as a CSV row, after a header row
+type csvWriter struct {
+	mu     sync.Mutex
+	writer *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) Write(result *config.Result) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	row := []string{
+		result.URL,
+		strconv.Itoa(result.StatusCode),
+		strconv.FormatInt(result.ContentSize, 10),
+		strconv.Itoa(result.Words),
+		strconv.Itoa(result.Lines),
+		result.Method,
+		FormatPairs(result.Input),
+		result.RedirectLocation,
+		result.ContentType,
+		FormatPairs(result.Captures),
+		formatCluster(result.Cluster),
+		strconv.FormatInt(result.DurationMs, 10),
+		result.Timestamp.Format(time.RFC3339),
+	}
+	if err := c.writer.Write(row); err != nil {
+		return err
+	}
+	// Flush every row, so the file is complete if the scan is killed
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// writeHeader writes the header row once
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.writer.Write(csvHeader)
+}
+
+// formatCluster returns the similarity cluster, empty without -dedupe-threshold
+func formatCluster(cluster int) string {
+	if cluster == 0 {
+		return ""
+	}
+	return strconv.Itoa(cluster)
+}
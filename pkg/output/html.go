@@ -0,0 +1,200 @@
+package output
+
+import (
+	"html/template"
+	"io"
+	neturl "net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+)
+
+// htmlWriter keeps the results, and writes them as a single HTML report on close
+type htmlWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	info    []Field
+	results []config.Result
+}
+
+func (h *htmlWriter) Write(result *config.Result) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.results = append(h.results, *result)
+	return nil
+}
+
+func (h *htmlWriter) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Results are listed in the order they were requested, grouped per host
+	sort.SliceStable(h.results, func(i, j int) bool {
+		return h.results[i].Job < h.results[j].Job
+	})
+	var hosts []*htmlHost
+	byHost := make(map[string]*htmlHost)
+	for _, result := range h.results {
+		name := result.URL
+		if u, err := neturl.Parse(result.URL); err == nil && u.Host != "" {
+			name = u.Host
+		}
+		host, ok := byHost[name]
+		if !ok {
+			host = &htmlHost{Name: name}
+			byHost[name] = host
+			hosts = append(hosts, host)
+		}
+		host.Results = append(host.Results, htmlResult{Result: result, Input: FormatPairs(result.Input), Captures: FormatPairs(result.Captures)})
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].Name < hosts[j].Name
+	})
+
+	return htmlTemplate.Execute(h.w, htmlReport{
+		Version:   config.Version,
+		Generated: time.Now().Format(time.RFC1123),
+		Info:      h.info,
+		Total:     len(h.results),
+		Hosts:     hosts,
+	})
+}
+
+// htmlReport is the data of the HTML report
+type htmlReport struct {
+	Version   string
+	Generated string
+	Info      []Field
+	Total     int
+	Hosts     []*htmlHost
+}
+
+// htmlHost is the results of a single host
+type htmlHost struct {
+	Name    string
+	Results []htmlResult
+}
+
+// htmlResult is a result with its inputs and captures formatted for display
+type htmlResult struct {
+	config.Result
+	Input    string
+	Captures string
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"class": func(status int) int { return status / 100 },
+	"time":  func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>qfuzz report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; background: #fafafa; }
+h1 { margin-bottom: 0; }
+.meta { color: #666; margin-top: 0.3em; }
+.config { border-collapse: collapse; margin: 1em 0; }
+.config td { padding: 0.2em 1em 0.2em 0; }
+.config td:first-child { font-weight: bold; }
+#filter { width: 100%; max-width: 40em; padding: 0.5em; font-size: 1em; margin: 1em 0; box-sizing: border-box; }
+details { margin: 1em 0; background: #fff; border: 1px solid #ddd; border-radius: 4px; }
+summary { padding: 0.6em; cursor: pointer; font-weight: bold; }
+table.results { border-collapse: collapse; width: 100%; font-size: 0.9em; }
+table.results th, table.results td { border-top: 1px solid #eee; padding: 0.4em 0.6em; text-align: left; vertical-align: top; }
+table.results th { background: #f0f0f0; cursor: pointer; user-select: none; white-space: nowrap; }
+table.results th.asc::after { content: " \25B2"; }
+table.results th.desc::after { content: " \25BC"; }
+table.results td { word-break: break-all; }
+.s2 { color: #1a7f37; } .s3 { color: #0969da; } .s4 { color: #9a6700; } .s5 { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>qfuzz report</h1>
+<p class="meta">qfuzz {{.Version}}, generated {{.Generated}}, {{.Total}} results</p>
+<table class="config">
+{{- range .Info}}
+<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+<input id="filter" type="search" placeholder="Filter results">
+{{- range .Hosts}}
+<details open class="host">
+<summary>{{.Name}} (<span class="count">{{len .Results}}</span>)</summary>
+<table class="results">
+<thead><tr>
+<th>URL</th><th data-type="num">Status</th><th data-type="num">Length</th><th data-type="num">Words</th><th data-type="num">Lines</th><th>Method</th><th>Input</th><th>Redirect</th><th>Content-Type</th><th>Captures</th><th data-type="num">Cluster</th><th data-type="num">Duration (ms)</th><th>Time</th>
+</tr></thead>
+<tbody>
+{{- range .Results}}
+<tr>
+<td><a href="{{.URL}}">{{.URL}}</a></td>
+<td class="s{{class .StatusCode}}">{{.StatusCode}}</td>
+<td>{{.ContentSize}}</td>
+<td>{{.Words}}</td>
+<td>{{.Lines}}</td>
+<td>{{.Method}}</td>
+<td>{{.Input}}</td>
+<td>{{.RedirectLocation}}</td>
+<td>{{.ContentType}}</td>
+<td>{{.Captures}}</td>
+<td>{{if .Cluster}}{{.Cluster}}{{end}}</td>
+<td>{{.DurationMs}}</td>
+<td>{{time .Timestamp}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+</details>
+{{- end}}
+<script>
+(function () {
+  var filter = document.getElementById("filter");
+  filter.addEventListener("input", function () {
+    var text = filter.value.toLowerCase();
+    document.querySelectorAll("details.host").forEach(function (host) {
+      var visible = 0;
+      host.querySelectorAll("tbody tr").forEach(function (row) {
+        var show = row.textContent.toLowerCase().indexOf(text) !== -1;
+        row.style.display = show ? "" : "none";
+        if (show) {
+          visible++;
+        }
+      });
+      host.querySelector(".count").textContent = visible;
+      host.style.display = visible > 0 ? "" : "none";
+    });
+  });
+
+  document.querySelectorAll("table.results").forEach(function (table) {
+    var headers = table.querySelectorAll("th");
+    headers.forEach(function (th, column) {
+      th.addEventListener("click", function () {
+        var asc = !th.classList.contains("asc");
+        headers.forEach(function (other) {
+          other.classList.remove("asc", "desc");
+        });
+        th.classList.add(asc ? "asc" : "desc");
+
+        var numeric = th.dataset.type === "num";
+        var body = table.tBodies[0];
+        var rows = Array.prototype.slice.call(body.rows);
+        rows.sort(function (a, b) {
+          var x = a.cells[column].textContent;
+          var y = b.cells[column].textContent;
+          var order = numeric ? Number(x) - Number(y) : x.localeCompare(y);
+          return asc ? order : -order;
+        });
+        rows.forEach(function (row) {
+          body.appendChild(row);
+        });
+      });
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`))
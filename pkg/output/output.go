@@ -0,0 +1,144 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SpeedyQweku/qfuzz/pkg/config"
+)
+
+// Output formats for -of
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+	FormatHTML  = "html"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{FormatText, FormatJSON, FormatJSONL, FormatCSV, FormatHTML}
+
+// Field is a line of the scan configuration, as printed in the banner
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Writer writes the results To the output file, it is safe for concurrent use
+type Writer interface {
+	Write(result *config.Result) error
+	Close() error
+}
+
+// FormatPairs returns the inputs or captures as name=value pairs, sorted by name
+func FormatPairs(values map[string]string) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + values[name]
+	}
+	return strings.Join(pairs, " ")
+}
+
+// New returns a writer for the format, the scan configuration is written in the HTML report header
+func New(format string, w io.Writer, info []Field) (Writer, error) {
+	switch format {
+	case FormatText, "":
+		return &textWriter{w: w}, nil
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return &jsonlWriter{encoder: encoder}, nil
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case FormatHTML:
+		return &htmlWriter{w: w, info: info}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// textWriter writes the URL of each result with its size, words, lines and status, one per line
+type textWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (t *textWriter) Write(result *config.Result) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := fmt.Fprintf(t.w, "%s [ContentSize: %d, Words: %d, Lines: %d, Status: %d]\n", result.URL, result.ContentSize, result.Words, result.Lines, result.StatusCode)
+	return err
+}
+
+func (t *textWriter) Close() error {
+	return nil
+}
+
+// jsonlWriter writes each result as a JSON object, one per line
+type jsonlWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func (j *jsonlWriter) Write(result *config.Result) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.encoder.Encode(result)
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
+
+// jsonWriter writes the results as a JSON array, streamed as they come
+type jsonWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	count int
+}
+
+func (j *jsonWriter) Write(result *config.Result) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if j.count == 0 {
+		separator = "[\n  "
+	}
+	j.count++
+	_, err := fmt.Fprintf(j.w, "%s%s", separator, bytes.TrimRight(data.Bytes(), "\n"))
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.count == 0 {
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
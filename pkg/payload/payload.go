@@ -0,0 +1,106 @@
+package payload
+
+import (
+	"sort"
+	"strings"
+)
+
+// Escape is the prefix that makes a keyword literal (e.g., \FUZZ is sent as FUZZ).
+const Escape = `\`
+
+// Replace substitutes every keyword occurrence in data with its word, in a single pass.
+// Escaped keywords are written as the literal keyword, and longer keywords are tried
+// first so that FUZZ does not clobber FUZZ2.
+func Replace(data string, inputs map[string]string) string {
+	if len(inputs) == 0 || data == "" {
+		return data
+	}
+	keywords := sortedKeywords(inputs)
+
+	var builder strings.Builder
+	builder.Grow(len(data))
+	for i := 0; i < len(data); {
+		if strings.HasPrefix(data[i:], Escape) {
+			if keyword := keywordAt(data, i+len(Escape), keywords); keyword != "" {
+				builder.WriteString(keyword)
+				i += len(Escape) + len(keyword)
+				continue
+			}
+		}
+		if keyword := keywordAt(data, i, keywords); keyword != "" {
+			builder.WriteString(inputs[keyword])
+			i += len(keyword)
+			continue
+		}
+		builder.WriteByte(data[i])
+		i++
+	}
+	return builder.String()
+}
+
+// Contains reports whether data holds at least one unescaped keyword.
+func Contains(data string, inputs map[string]string) bool {
+	if len(inputs) == 0 || data == "" {
+		return false
+	}
+	keywords := sortedKeywords(inputs)
+
+	for i := 0; i < len(data); i++ {
+		if strings.HasPrefix(data[i:], Escape) {
+			if keyword := keywordAt(data, i+len(Escape), keywords); keyword != "" {
+				i += len(Escape) + len(keyword) - 1
+				continue
+			}
+		}
+		if keywordAt(data, i, keywords) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsAny reports whether any of the items holds at least one unescaped keyword.
+func ContainsAny(items []string, inputs map[string]string) bool {
+	for _, item := range items {
+		if Contains(item, inputs) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceAll substitutes the keywords in every item, returning a new slice.
+func ReplaceAll(items []string, inputs map[string]string) []string {
+	replaced := make([]string, len(items))
+	for i, item := range items {
+		replaced[i] = Replace(item, inputs)
+	}
+	return replaced
+}
+
+// keywordAt returns the keyword starting at data[i], or "" if there is none.
+func keywordAt(data string, i int, keywords []string) string {
+	for _, keyword := range keywords {
+		if strings.HasPrefix(data[i:], keyword) {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// sortedKeywords returns the keywords ordered from the longest To the shortest.
+func sortedKeywords(inputs map[string]string) []string {
+	keywords := make([]string, 0, len(inputs))
+	for keyword := range inputs {
+		if keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if len(keywords[i]) != len(keywords[j]) {
+			return len(keywords[i]) > len(keywords[j])
+		}
+		return keywords[i] < keywords[j]
+	})
+	return keywords
+}
@@ -0,0 +1,65 @@
+package payload
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		inputs map[string]string
+		want   string
+	}{
+		{"no inputs", "/FUZZ", nil, "/FUZZ"},
+		{"empty data", "", map[string]string{"FUZZ": "admin"}, ""},
+		{"single", "/FUZZ", map[string]string{"FUZZ": "admin"}, "/admin"},
+		{"every occurrence", "/FUZZ/FUZZ.bak", map[string]string{"FUZZ": "admin"}, "/admin/admin.bak"},
+		{"several keywords", "user=USER&pass=PASS", map[string]string{"USER": "root", "PASS": "toor"}, "user=root&pass=toor"},
+		{"longest keyword first", "FUZZ2-FUZZ", map[string]string{"FUZZ": "a", "FUZZ2": "b"}, "b-a"},
+		{"word not replaced again", "FUZZ", map[string]string{"FUZZ": "USER", "USER": "root"}, "USER"},
+		{"escaped keyword", `/\FUZZ/FUZZ`, map[string]string{"FUZZ": "admin"}, "/FUZZ/admin"},
+		{"escaped longest keyword", `\FUZZ2`, map[string]string{"FUZZ": "a", "FUZZ2": "b"}, "FUZZ2"},
+		{"lone backslash", `a\b FUZZ`, map[string]string{"FUZZ": "admin"}, `a\b admin`},
+		{"double backslash", `\\FUZZ`, map[string]string{"FUZZ": "admin"}, `\FUZZ`},
+		{"trailing backslash", `FUZZ\`, map[string]string{"FUZZ": "admin"}, `admin\`},
+		{"empty keyword ignored", "/FUZZ", map[string]string{"": "x", "FUZZ": "admin"}, "/admin"},
+		{"empty word", "/FUZZ", map[string]string{"FUZZ": ""}, "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Replace(tt.data, tt.inputs); got != tt.want {
+				t.Errorf("Replace(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	inputs := map[string]string{"FUZZ": "admin", "FUZZ2": "x"}
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{"", false},
+		{"/admin", false},
+		{"/FUZZ", true},
+		{`/\FUZZ`, false},
+		{`/\FUZZ2`, false},
+		{`/\FUZZ/FUZZ2`, true},
+		{`\\FUZZ`, false},
+	}
+	for _, tt := range tests {
+		if got := Contains(tt.data, inputs); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+
+	if ContainsAny([]string{"/a", `\FUZZ`}, inputs) {
+		t.Errorf("ContainsAny without an unescaped keyword = true")
+	}
+	if got := ReplaceAll([]string{"X-A: FUZZ", "X-B: b"}, inputs); !slices.Equal(got, []string{"X-A: admin", "X-B: b"}) {
+		t.Errorf("ReplaceAll() = %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Package ratelimit implements the token bucket of -rate, shared by all the workers
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket refilled with rate tokens per second, each request takes a token.
+// The bucket holds a single token, so the requests are spread evenly over each second.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   int
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter allowing rate requests per second, 0 means no limit
+func New(rate int) *Limiter {
+	return &Limiter{rate: rate, tokens: 1, last: time.Now()}
+}
+
+// Rate returns the number of requests per second, 0 means no limit
+func (l *Limiter) Rate() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// SetRate changes the number of requests per second, the waiting requests pick it up right away
+func (l *Limiter) SetRate(rate int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = rate
+}
+
+// Wait blocks until a token is available, or returns the error of the context once it is canceled
+func (l *Limiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.rate <= 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		l.refill(time.Now())
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		// Wait for the missing part of a token, at most a second so a raised rate is picked up
+		wait := time.Duration((1 - l.tokens) / float64(l.rate) * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(min(wait, time.Second))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Take takes a token without blocking, it returns 0 once taken or else how long until a token is available
+func (l *Limiter) Take() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	l.refill(time.Now())
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return max(time.Millisecond, time.Duration((1-l.tokens)/float64(l.rate)*float64(time.Second)))
+}
+
+// refill adds the tokens earned since the last refill, the bucket holds a single token
+func (l *Limiter) refill(now time.Time) {
+	if l.rate > 0 {
+		l.tokens = min(1, l.tokens+now.Sub(l.last).Seconds()*float64(l.rate))
+	}
+	l.last = now
+}
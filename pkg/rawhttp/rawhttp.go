@@ -0,0 +1,442 @@
+// Package rawhttp sends the requests of a raw request file over HTTP/1.1, with the header lines
+// written in the order and casing of the file, which net/http sorts and canonicalizes
+package rawhttp
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// orderKey is the context key of the header order
+type orderKey struct{}
+
+// WithOrder returns a copy of the context carrying the header names in the order they are written
+func WithOrder(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, orderKey{}, names)
+}
+
+// Transport writes the header lines of a request in the order of its context, and in the casing
+// of that order, the requests without an order are sent by the base transport.
+// Nothing is added To the headers but Host and Content-Length, and only when the order lacks them.
+type Transport struct {
+	Base *http.Transport // Base gives the proxy, the dialer, the TLS config and the idle connection limits.
+
+	mu   sync.Mutex
+	idle map[string][]*conn
+}
+
+// conn is a connection To a target, kept idle between requests
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+	key    string
+	since  time.Time
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	names, ok := req.Context().Value(orderKey{}).([]string)
+	if !ok || (req.URL.Scheme != "http" && req.URL.Scheme != "https") {
+		return t.Base.RoundTrip(req)
+	}
+
+	var proxyURL *neturl.URL
+	if t.Base.Proxy != nil {
+		var err error
+		if proxyURL, err = t.Base.Proxy(req); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+	key := req.URL.Scheme + "://" + address(req.URL)
+	if proxyURL != nil {
+		key += " via " + proxyURL.String()
+	}
+
+	// A kept connection may have been closed by the server, the request is sent again on a new one
+	if c := t.get(key); c != nil {
+		resp, err := t.send(req, c, names, proxyURL)
+		if err == nil || req.Context().Err() != nil || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+
+	c, err := t.dial(req.Context(), req.URL, proxyURL)
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+	c.key = key
+	return t.send(req, c, names, proxyURL)
+}
+
+// send writes the request on the connection and reads its response, the connection is kept
+// once the body is read, unless the request or the response closes it
+func (t *Transport) send(req *http.Request, c *conn, names []string, proxyURL *neturl.URL) (*http.Response, error) {
+	stop := context.AfterFunc(req.Context(), func() { c.Close() })
+	if deadline, ok := req.Context().Deadline(); ok {
+		c.SetDeadline(deadline)
+	} else {
+		c.SetDeadline(time.Time{})
+	}
+
+	// Plain HTTP goes To an HTTP proxy as is, the other targets are tunneled
+	if proxyURL != nil && (req.URL.Scheme != "http" || strings.HasPrefix(proxyURL.Scheme, "socks5")) {
+		proxyURL = nil
+	}
+	w := bufio.NewWriter(c)
+	err := write(w, req, names, proxyURL)
+	if err == nil {
+		err = w.Flush()
+	}
+	var resp *http.Response
+	if err == nil {
+		resp, err = http.ReadResponse(c.reader, req)
+	}
+	if err != nil {
+		stop()
+		c.Close()
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	keep := !req.Close && !resp.Close
+	resp.Body = &body{ReadCloser: resp.Body, release: func(clean bool) {
+		if stop() && clean && keep {
+			t.put(c)
+			return
+		}
+		c.Close()
+	}}
+	decode(resp)
+	return resp, nil
+}
+
+// write writes the request line, the header lines and the body of the request. The headers named
+// in names are written first in that order and casing, the other ones after them sorted by name.
+// With an HTTP proxy, the request line holds the full URL.
+func write(w io.Writer, req *http.Request, names []string, proxyURL *neturl.URL) error {
+	target := req.URL.RequestURI()
+	if proxyURL != nil {
+		target = req.URL.String()
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	fmt.Fprintf(w, "%s %s HTTP/1.1\r\n", req.Method, target)
+
+	hasHost, hasLength := false, false
+	for _, name := range names {
+		hasHost = hasHost || strings.EqualFold(name, "Host")
+		hasLength = hasLength || strings.EqualFold(name, "Content-Length")
+	}
+	if !hasHost {
+		fmt.Fprintf(w, "Host: %s\r\n", host)
+	}
+	if proxyURL != nil && proxyURL.User != nil {
+		fmt.Fprintf(w, "Proxy-Authorization: %s\r\n", basicAuth(proxyURL.User))
+	}
+
+	// A body is announced where the file has its Content-Length, or after the headers
+	length := req.ContentLength
+	needsLength := length > 0 || req.Method == "POST" || req.Method == "PUT" || req.Method == "PATCH"
+	used := make(map[string]int)
+	for _, name := range names {
+		switch {
+		case strings.EqualFold(name, "Host"):
+			if used["Host"] == 0 {
+				fmt.Fprintf(w, "%s: %s\r\n", name, host)
+			}
+			used["Host"]++
+			continue
+		case strings.EqualFold(name, "Content-Length"):
+			if used["Content-Length"] == 0 {
+				fmt.Fprintf(w, "%s: %d\r\n", name, max(length, 0))
+			}
+			used["Content-Length"]++
+			continue
+		}
+		// The header is stored under its own casing, or canonicalized (User-Agent)
+		key := name
+		if _, ok := req.Header[key]; !ok {
+			key = http.CanonicalHeaderKey(name)
+		}
+		if values := req.Header[key]; used[key] < len(values) {
+			fmt.Fprintf(w, "%s: %s\r\n", name, values[used[key]])
+			used[key]++
+		}
+	}
+
+	keys := make([]string, 0, len(req.Header))
+	for key := range req.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.EqualFold(key, "Host") || strings.EqualFold(key, "Content-Length") {
+			continue
+		}
+		for _, value := range req.Header[key][min(used[key], len(req.Header[key])):] {
+			fmt.Fprintf(w, "%s: %s\r\n", key, value)
+		}
+	}
+	if !hasLength && needsLength {
+		fmt.Fprintf(w, "Content-Length: %d\r\n", max(length, 0))
+	}
+	io.WriteString(w, "\r\n")
+
+	if req.Body == nil {
+		return nil
+	}
+	defer req.Body.Close()
+	_, err := io.Copy(w, req.Body)
+	return err
+}
+
+// dial connects To the target, through the proxy if there is one, and does the TLS handshake of an HTTPS target
+func (t *Transport) dial(ctx context.Context, target *neturl.URL, proxyURL *neturl.URL) (*conn, error) {
+	dial := t.Base.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	addr := address(target)
+
+	var c net.Conn
+	var err error
+	switch {
+	case proxyURL == nil:
+		c, err = dial(ctx, "tcp", addr)
+	case strings.HasPrefix(proxyURL.Scheme, "socks5"):
+		var d proxy.Dialer
+		if d, err = proxy.FromURL(proxyURL, dialer(dial)); err == nil {
+			c, err = d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+		}
+	default:
+		if c, err = dial(ctx, "tcp", address(proxyURL)); err != nil {
+			break
+		}
+		if proxyURL.Scheme == "https" {
+			if c, err = handshake(ctx, c, t.tlsConfig(proxyURL.Hostname())); err != nil {
+				break
+			}
+		}
+		if target.Scheme == "https" {
+			err = connect(c, addr, proxyURL)
+		}
+	}
+	if err == nil && target.Scheme == "https" {
+		c, err = handshake(ctx, c, t.tlsConfig(target.Hostname()))
+	}
+	if err != nil {
+		if c != nil {
+			c.Close()
+		}
+		return nil, err
+	}
+	return &conn{Conn: c, reader: bufio.NewReader(c)}, nil
+}
+
+// tlsConfig returns the TLS config of the base transport for the server, limited To HTTP/1.1
+func (t *Transport) tlsConfig(server string) *tls.Config {
+	config := &tls.Config{}
+	if t.Base.TLSClientConfig != nil {
+		config = t.Base.TLSClientConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = server
+	}
+	config.NextProtos = []string{"http/1.1"}
+	return config
+}
+
+// handshake does the TLS handshake on the connection, closing it on failure
+func handshake(ctx context.Context, c net.Conn, config *tls.Config) (net.Conn, error) {
+	tlsConn := tls.Client(c, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// connect opens a tunnel To addr through the HTTP proxy on the connection
+func connect(c net.Conn, addr string, proxyURL *neturl.URL) error {
+	fmt.Fprintf(c, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if proxyURL.User != nil {
+		fmt.Fprintf(c, "Proxy-Authorization: %s\r\n", basicAuth(proxyURL.User))
+	}
+	io.WriteString(c, "\r\n")
+
+	// The server sends nothing more before the TLS handshake, the reader can be dropped after the response
+	resp, err := http.ReadResponse(bufio.NewReaderSize(c, 1), &http.Request{Method: "CONNECT"})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy CONNECT %s: %s", addr, resp.Status)
+	}
+	return nil
+}
+
+// get returns a kept connection for the key, nil if there is none
+func (t *Transport) get(key string) *conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for conns := t.idle[key]; len(conns) > 0; conns = t.idle[key] {
+		c := conns[len(conns)-1]
+		t.idle[key] = conns[:len(conns)-1]
+		if t.Base.IdleConnTimeout > 0 && time.Since(c.since) > t.Base.IdleConnTimeout {
+			c.Close()
+			continue
+		}
+		return c
+	}
+	return nil
+}
+
+// put keeps the connection for the next request, within the idle limits of the base transport
+func (t *Transport) put(c *conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.idle == nil {
+		t.idle = make(map[string][]*conn)
+	}
+	total := 0
+	for _, conns := range t.idle {
+		total += len(conns)
+	}
+	perHost := t.Base.MaxIdleConnsPerHost
+	if perHost <= 0 {
+		perHost = http.DefaultMaxIdleConnsPerHost
+	}
+	if len(t.idle[c.key]) >= perHost || (t.Base.MaxIdleConns > 0 && total >= t.Base.MaxIdleConns) {
+		c.Close()
+		return
+	}
+	c.since = time.Now()
+	t.idle[c.key] = append(t.idle[c.key], c)
+}
+
+// body releases the connection once the response body is read or closed
+type body struct {
+	io.ReadCloser
+	once    sync.Once
+	release func(clean bool)
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(func() { b.release(errors.Is(err, io.EOF)) })
+	}
+	return n, err
+}
+
+func (b *body) Close() error {
+	// Closing a response body reads what is left of it, so the connection can be kept
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.release(err == nil) })
+	return err
+}
+
+// decode decompresses a gzip or deflate response body, as net/http does for the gzip it asks for
+func decode(resp *http.Response) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding != "gzip" && encoding != "deflate" {
+		return
+	}
+	resp.Body = &decoder{body: resp.Body, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// decoder decompresses a body, the reader is made on the first read
+type decoder struct {
+	body     io.ReadCloser
+	encoding string
+	reader   io.Reader
+	err      error
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	if d.reader == nil && d.err == nil {
+		if d.encoding == "gzip" {
+			d.reader, d.err = gzip.NewReader(d.body)
+		} else {
+			d.reader, d.err = zlib.NewReader(d.body)
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.reader.Read(p)
+}
+
+func (d *decoder) Close() error {
+	return d.body.Close()
+}
+
+// dialer adapts a dial function To the dialers of golang.org/x/net/proxy
+type dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (d dialer) Dial(network, addr string) (net.Conn, error) {
+	return d(context.Background(), network, addr)
+}
+
+func (d dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d(ctx, network, addr)
+}
+
+// address returns the host:port of the URL, with the default port of its scheme
+func address(u *neturl.URL) string {
+	if port := u.Port(); port != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	if strings.HasPrefix(u.Scheme, "socks5") {
+		return net.JoinHostPort(u.Hostname(), "1080")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// basicAuth returns the Proxy-Authorization value of the proxy credentials
+func basicAuth(user *neturl.Userinfo) string {
+	password, _ := user.Password()
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password))
+}
+
+// closeBody closes the body of a request that is not sent
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
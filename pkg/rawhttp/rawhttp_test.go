@@ -0,0 +1,199 @@
+package rawhttp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// capture is a server that records the raw requests it gets, and answers them with "ok"
+type capture struct {
+	addr     string
+	conns    atomic.Int32
+	requests chan string
+}
+
+func newCapture(t *testing.T) *capture {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	c := &capture{addr: listener.Addr().String(), requests: make(chan string, 16)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			c.conns.Add(1)
+			go c.serve(conn)
+		}
+	}()
+	return c
+}
+
+// serve reads the requests of the connection as they are written, head and body
+func (c *capture) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		var request strings.Builder
+		length := 0
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			request.WriteString(line)
+			if line == "\r\n" {
+				break
+			}
+			if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+				length, _ = strconv.Atoi(strings.TrimSpace(value))
+			}
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+		request.Write(body)
+		c.requests <- request.String()
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := newCapture(t)
+	client := &http.Client{Transport: &Transport{Base: &http.Transport{}}}
+
+	tests := []struct {
+		name   string
+		method string
+		host   string
+		names  []string
+		header http.Header
+		body   string
+		want   string
+	}{
+		{
+			name:   "file order and casing",
+			method: "POST",
+			host:   "example.com",
+			names:  []string{"Host", "user-agent", "accept-encoding", "X-B", "X-A", "Content-Length"},
+			header: http.Header{"User-Agent": {"ua"}, "accept-encoding": {"gzip"}, "X-B": {"b"}, "X-A": {"a"}, "Content-Length": {"99"}},
+			body:   "hello",
+			want: "POST /path?q=1 HTTP/1.1\r\nHost: example.com\r\nuser-agent: ua\r\naccept-encoding: gzip\r\n" +
+				"X-B: b\r\nX-A: a\r\nContent-Length: 5\r\n\r\nhello",
+		},
+		{
+			name:   "host first and the other headers last",
+			method: "GET",
+			names:  []string{"X-A"},
+			header: http.Header{"X-A": {"a"}, "X-Extra": {"1"}},
+			want:   "GET /path?q=1 HTTP/1.1\r\nHost: " + server.addr + "\r\nX-A: a\r\nX-Extra: 1\r\n\r\n",
+		},
+		{
+			name:   "repeated headers",
+			method: "GET",
+			names:  []string{"Cookie", "X", "Cookie"},
+			header: http.Header{"Cookie": {"a=1", "b=2"}, "X": {"x"}},
+			want:   "GET /path?q=1 HTTP/1.1\r\nHost: " + server.addr + "\r\nCookie: a=1\r\nX: x\r\nCookie: b=2\r\n\r\n",
+		},
+		{
+			name:   "body without Content-Length",
+			method: "PUT",
+			names:  []string{"X-A"},
+			header: http.Header{"X-A": {"a"}},
+			body:   "data",
+			want:   "PUT /path?q=1 HTTP/1.1\r\nHost: " + server.addr + "\r\nX-A: a\r\nContent-Length: 4\r\n\r\ndata",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithOrder(context.Background(), tt.names)
+			req, err := http.NewRequestWithContext(ctx, tt.method, "http://"+server.addr+"/path?q=1", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header = tt.header
+			req.Host = tt.host
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "ok" {
+				t.Errorf("body = %q, want ok", body)
+			}
+			if got := <-server.requests; got != tt.want {
+				t.Errorf("request =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+
+	// The requests are sent on a single kept connection
+	if conns := server.conns.Load(); conns != 1 {
+		t.Errorf("connections = %d, want 1", conns)
+	}
+}
+
+func TestTransportProxy(t *testing.T) {
+	proxy := newCapture(t)
+	proxyURL := &neturl.URL{Scheme: "http", Host: proxy.addr, User: neturl.UserPassword("user", "pass")}
+	client := &http.Client{Transport: &Transport{Base: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}}
+
+	ctx := WithOrder(context.Background(), []string{"Host", "X-A"})
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/a", nil)
+	req.Header = http.Header{"X-A": {"a"}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	want := "GET http://example.com/a HTTP/1.1\r\nProxy-Authorization: Basic dXNlcjpwYXNz\r\nHost: example.com\r\nX-A: a\r\n\r\n"
+	if got := <-proxy.requests; got != want {
+		t.Errorf("request =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestTransportDecode(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	io.WriteString(zw, "<html>admin</html>")
+	zw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{Base: &http.Transport{}}}
+	ctx := WithOrder(context.Background(), []string{"accept-encoding"})
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	req.Header = http.Header{"accept-encoding": {"gzip"}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "<html>admin</html>" || resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("body = %q, Content-Encoding = %q, want the decoded body", body, resp.Header.Get("Content-Encoding"))
+	}
+}